@@ -9,6 +9,11 @@ integration {
     name = "DigitalOcean Image"
     slug = "digitalocean-image"
   }
+  component {
+    type = "data-source"
+    name = "DigitalOcean Images"
+    slug = "digitalocean-images"
+  }
   component {
     type = "builder"
     name = "DigitalOcean"
@@ -19,4 +24,14 @@ integration {
     name = "DigitalOcean Import"
     slug = "digitalocean-import"
   }
+  component {
+    type = "post-processor"
+    name = "DigitalOcean Retention"
+    slug = "digitalocean-retention"
+  }
+  component {
+    type = "post-processor"
+    name = "DigitalOcean Cleanup"
+    slug = "digitalocean-cleanup"
+  }
 }
@@ -57,6 +57,15 @@ func (a *Artifact) State(name string) interface{} {
 }
 
 func (a *Artifact) Destroy() error {
+	if volumeSnapshotIds, ok := a.StateData["volume_snapshot_ids"].([]string); ok {
+		for _, id := range volumeSnapshotIds {
+			log.Printf("Destroying volume snapshot: %s", id)
+			if _, err := a.Client.Storage.DeleteSnapshot(context.TODO(), id); err != nil {
+				return err
+			}
+		}
+	}
+
 	log.Printf("Destroying image: %d (%s)", a.SnapshotId, a.SnapshotName)
 	_, err := a.Client.Images.Delete(context.TODO(), a.SnapshotId)
 	return err
@@ -92,6 +101,11 @@ func (a *Artifact) stateHCPPackerRegistryMetadata() interface{} {
 		if ok {
 			labels["droplet_name"] = drpName
 		}
+		// Get and set the volume snapshot IDs
+		volumeSnapshotIds, ok := a.StateData["volume_snapshot_ids"].([]string)
+		if ok && len(volumeSnapshotIds) > 0 {
+			labels["volume_snapshot_ids"] = strings.Join(volumeSnapshotIds, ",")
+		}
 		// instantiate the image
 		img, err := registryimage.FromArtifact(a,
 			registryimage.WithSourceID(sourceID),
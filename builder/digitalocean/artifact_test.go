@@ -121,3 +121,33 @@ func TestArtifactState_hcpPackerRegistryMetadata(t *testing.T) {
 		t.Fatalf("Bad: expected %#v got %#v", expected, images)
 	}
 }
+
+func TestArtifactState_hcpPackerRegistryMetadataVolumeSnapshots(t *testing.T) {
+	artifact := &Artifact{
+		SnapshotName: "snapshot-1",
+		SnapshotId:   12345,
+		RegionNames:  []string{"nyc3"},
+		StateData: map[string]interface{}{
+			"source_image_id":     "centos-stream-8-x64",
+			"volume_snapshot_ids": []string{"snap-1", "snap-2"},
+		},
+	}
+
+	var images []registryimage.Image
+	err := mapstructure.Decode(artifact.State(registryimage.ArtifactStateURI), &images)
+	if err != nil {
+		t.Fatalf("Bad: unexpected error when trying to decode state into registryimage.Image %v", err)
+	}
+
+	if len(images) != 1 {
+		t.Fatalf("Bad: we should have one image for this test Artifact but we got %d", len(images))
+	}
+
+	expected := map[string]string{
+		"source_image_id":     "centos-stream-8-x64",
+		"volume_snapshot_ids": "snap-1,snap-2",
+	}
+	if !reflect.DeepEqual(images[0].Labels, expected) {
+		t.Fatalf("Bad: expected %#v got %#v", expected, images[0].Labels)
+	}
+}
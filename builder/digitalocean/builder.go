@@ -7,17 +7,13 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"net/url"
 
-	"github.com/digitalocean/godo"
-	"github.com/digitalocean/packer-plugin-digitalocean/version"
+	docommon "github.com/digitalocean/packer-plugin-digitalocean/common"
 	"github.com/hashicorp/hcl/v2/hcldec"
 	"github.com/hashicorp/packer-plugin-sdk/communicator"
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
 	"github.com/hashicorp/packer-plugin-sdk/multistep/commonsteps"
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
-	"github.com/hashicorp/packer-plugin-sdk/useragent"
-	"golang.org/x/oauth2"
 )
 
 // The unique id for the builder
@@ -43,67 +39,32 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, []string, error) {
 		return nil, warnings, errs
 	}
 
-	return nil, warnings, nil
+	generatedData := []string{"VolumeMountPaths"}
+	return generatedData, warnings, nil
 }
 
 func (b *Builder) Run(ctx context.Context, ui packersdk.Ui, hook packersdk.Hook) (packersdk.Artifact, error) {
-	ua := useragent.String(version.PluginVersion.FormattedVersion())
-	opts := []godo.ClientOpt{godo.SetUserAgent(ua)}
-	if b.config.APIURL != "" {
-		_, err := url.Parse(b.config.APIURL)
-		if err != nil {
-			return nil, fmt.Errorf("DigitalOcean: Invalid API URL, %s.", err)
-		}
-
-		opts = append(opts, godo.SetBaseURL(b.config.APIURL))
-	}
-	if *b.config.HTTPRetryMax > 0 {
-		opts = append(opts, godo.WithRetryAndBackoffs(godo.RetryConfig{
-			RetryMax:     *b.config.HTTPRetryMax,
-			RetryWaitMin: b.config.HTTPRetryWaitMin,
-			RetryWaitMax: b.config.HTTPRetryWaitMax,
-			Logger:       log.Default(),
-		}))
-	}
-
-	client, err := godo.New(oauth2.NewClient(context.TODO(), &APITokenSource{
-		AccessToken: b.config.APIToken,
-	}), opts...)
+	client, err := b.config.Client()
 	if err != nil {
 		return nil, fmt.Errorf("DigitalOcean: could not create client, %s", err)
 	}
 
-	if len(b.config.SnapshotRegions) > 0 {
-		opt := &godo.ListOptions{
-			Page:    1,
-			PerPage: 200,
-		}
-		regions, _, err := client.Regions.List(context.TODO(), opt)
-		if err != nil {
-			return nil, fmt.Errorf("DigitalOcean: Unable to get regions, %s", err)
-		}
-
-		validRegions := make(map[string]struct{})
-		for _, val := range regions {
-			validRegions[val.Slug] = struct{}{}
-		}
-
-		for _, region := range append(b.config.SnapshotRegions, b.config.Region) {
-			if _, ok := validRegions[region]; !ok {
-				return nil, fmt.Errorf("DigitalOcean: Invalid region, %s", region)
-			}
-		}
-	}
-
 	// Set up the state
 	state := new(multistep.BasicStateBag)
 	state.Put("config", &b.config)
 	state.Put("client", client)
 	state.Put("hook", hook)
 	state.Put("ui", ui)
+	// Mark temporary resources as owned by Packer so that the ones left
+	// over from a crashed build can be cleaned up
+	state.Put("build_tags", docommon.BuildTags())
 
-	// Only generate the temp key pair if one is not already provided
-	genTempKeyPair := b.config.SSHKeyID == 0 || b.config.Comm.SSHPrivateKeyFile == ""
+	// Only generate the temp key pair if one is not already provided. When
+	// building from an existing droplet, the key could not be installed.
+	// Windows droplets are reached over WinRM and need no key.
+	sourceDroplet := b.config.SourceDropletID != 0
+	genTempKeyPair := !sourceDroplet && b.config.Comm.Type != "winrm" &&
+		(b.config.SSHKeyID == 0 || b.config.Comm.SSHPrivateKeyFile == "")
 
 	// Build the steps
 	steps := []multistep.Step{
@@ -113,20 +74,38 @@ func (b *Builder) Run(ctx context.Context, ui packersdk.Ui, hook packersdk.Hook)
 				SSHTemporaryKeyPair: b.config.Comm.SSH.SSHTemporaryKeyPair,
 			},
 		),
-		multistep.If(b.config.PackerDebug && b.config.Comm.SSHPrivateKeyFile == "",
+		multistep.If(b.config.PackerDebug && genTempKeyPair && b.config.Comm.SSHPrivateKeyFile == "",
 			&communicator.StepDumpSSHKey{
 				Path: fmt.Sprintf("do_%s.pem", b.config.PackerBuildName),
 				SSH:  &b.config.Comm.SSH,
 			},
 		),
-		multistep.If(genTempKeyPair, new(stepCreateSSHKey)),
-		new(stepCreateDroplet),
-		new(stepDropletInfo),
+		multistep.If(!sourceDroplet && b.config.ImageFilter != nil, new(stepSourceImage)),
+		new(stepPreflight),
+		multistep.If(genTempKeyPair && !b.config.UserDataSSHKey, new(stepCreateSSHKey)),
+		new(stepCreateVolumes),
+		multistep.If(b.config.TemporaryFirewall, new(stepCreateFirewall)),
+		multistep.If(sourceDroplet, new(stepSourceDroplet)),
+		multistep.If(b.config.Resume, new(stepResumeDroplet)),
+		multistep.If(!sourceDroplet, new(stepCreateDroplet)),
+		// A source droplet is snapshotted as is without a communicator, and
+		// may be off
+		multistep.If(!sourceDroplet || b.config.Comm.Type != "none", new(stepDropletInfo)),
 		&communicator.StepConnect{
 			Config:    &b.config.Comm,
 			Host:      communicator.CommHost(b.config.Comm.Host(), "droplet_ip"),
 			SSHConfig: b.config.Comm.SSHConfigFunc(),
+			WinRMConfig: func(multistep.StateBag) (*communicator.WinRMConfig, error) {
+				return &communicator.WinRMConfig{
+					Username: b.config.Comm.WinRMUser,
+					Password: b.config.Comm.WinRMPassword,
+				}, nil
+			},
 		},
+		multistep.If(b.config.WaitForCloudInit, &stepWaitForCloudInit{
+			timeout:      b.config.CloudInitTimeout,
+			pollInterval: b.config.PollInterval,
+		}),
 		new(commonsteps.StepProvision),
 		multistep.If(genTempKeyPair,
 			&commonsteps.StepCleanupTempKeys{
@@ -135,11 +114,31 @@ func (b *Builder) Run(ctx context.Context, ui packersdk.Ui, hook packersdk.Hook)
 		),
 		new(stepShutdown),
 		new(stepPowerOff),
+		new(stepDetachVolumes),
 		&stepSnapshot{
 			snapshotTimeout:         b.config.SnapshotTimeout,
 			transferTimeout:         b.config.TransferTimeout,
 			waitForSnapshotTransfer: *b.config.WaitSnapshotTransfer,
 		},
+		new(stepVolumeSnapshots),
+	}
+
+	// A dry run only looks up what the build depends on and prints what it
+	// would create
+	if b.config.DryRun {
+		steps = []multistep.Step{
+			multistep.If(genTempKeyPair,
+				&communicator.StepSSHKeyGen{
+					CommConf:            &b.config.Comm,
+					SSHTemporaryKeyPair: b.config.Comm.SSH.SSHTemporaryKeyPair,
+				},
+			),
+			multistep.If(!sourceDroplet && b.config.ImageFilter != nil, new(stepSourceImage)),
+			new(stepPreflight),
+			&stepDryRun{
+				tempSSHKey: genTempKeyPair && !b.config.UserDataSSHKey,
+			},
+		}
 	}
 
 	// Run the steps
@@ -151,6 +150,10 @@ func (b *Builder) Run(ctx context.Context, ui packersdk.Ui, hook packersdk.Hook)
 		return nil, rawErr.(error)
 	}
 
+	if b.config.DryRun {
+		return nil, nil
+	}
+
 	if _, ok := state.GetOk("snapshot_name"); !ok {
 		log.Println("Failed to find snapshot_name in state. Bug?")
 		return nil, nil
@@ -162,11 +165,12 @@ func (b *Builder) Run(ctx context.Context, ui packersdk.Ui, hook packersdk.Hook)
 		RegionNames:  state.Get("regions").([]string),
 		Client:       client,
 		StateData: map[string]interface{}{
-			"generated_data":  state.Get("generated_data"),
-			"source_image_id": state.Get("source_image_id"),
-			"droplet_size":    state.Get("droplet_size"),
-			"droplet_name":    state.Get("droplet_name"),
-			"build_region":    state.Get("build_region"),
+			"generated_data":      state.Get("generated_data"),
+			"source_image_id":     state.Get("source_image_id"),
+			"droplet_size":        state.Get("droplet_size"),
+			"droplet_name":        state.Get("droplet_name"),
+			"build_region":        state.Get("build_region"),
+			"volume_snapshot_ids": state.Get("volume_snapshot_ids"),
 		},
 	}
 
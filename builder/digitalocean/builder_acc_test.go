@@ -10,6 +10,7 @@ import (
 	"testing"
 
 	"github.com/digitalocean/godo"
+	docommon "github.com/digitalocean/packer-plugin-digitalocean/common"
 	"github.com/digitalocean/packer-plugin-digitalocean/version"
 	"github.com/google/uuid"
 	"github.com/hashicorp/packer-plugin-sdk/acctest"
@@ -141,7 +142,7 @@ func makeTemplateWithImageId(t *testing.T) string {
 				RetryMax: 5,
 			}),
 		}
-		client, err := godo.New(oauth2.NewClient(context.TODO(), &APITokenSource{
+		client, err := godo.New(oauth2.NewClient(context.TODO(), &docommon.APITokenSource{
 			AccessToken: token,
 		}), opts...)
 		if err != nil {
@@ -1,9 +1,17 @@
 package digitalocean
 
 import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
 	"strconv"
 	"testing"
 	"time"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/stretchr/testify/require"
 )
 
 func testConfig() map[string]interface{} {
@@ -404,3 +412,511 @@ func TestBuilderPrepare_ConnectWithPrivateIP(t *testing.T) {
 		t.Fatal("should not have error")
 	}
 }
+
+func TestBuilderPrepare_Volumes(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	// Test with a temporary volume without a size
+	config["volumes"] = []map[string]interface{}{
+		{"filesystem_type": "ext4"},
+	}
+	_, warnings, err := b.Prepare(config)
+	if len(warnings) > 0 {
+		t.Fatalf("bad: %#v", warnings)
+	}
+	if err == nil {
+		t.Fatal("should have error: 'volumes[0]: size is required'")
+	}
+
+	// Test with a temporary volume and an existing one
+	config["volumes"] = []map[string]interface{}{
+		{"size": 10, "filesystem_type": "ext4"},
+		{"volume_id": "506f78a4-e098-11e5-ad9f-000f53306ae1"},
+	}
+	b = Builder{}
+	_, warnings, err = b.Prepare(config)
+	if len(warnings) > 0 {
+		t.Fatalf("bad: %#v", warnings)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	if b.config.Volumes[0].Name == "" {
+		t.Errorf("volume name should have a default")
+	}
+
+	// Test with an invalid filesystem type
+	config["volumes"] = []map[string]interface{}{
+		{"size": 10, "filesystem_type": "ntfs"},
+	}
+	b = Builder{}
+	_, warnings, err = b.Prepare(config)
+	if len(warnings) > 0 {
+		t.Fatalf("bad: %#v", warnings)
+	}
+	if err == nil {
+		t.Fatal("should have error: 'volumes[0]: filesystem_type must be one of ext4 or xfs'")
+	}
+}
+
+func TestBuilderPrepare_VolumeSnapshots(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	// Test without any volumes
+	config["volume_snapshots"] = true
+	_, warnings, err := b.Prepare(config)
+	if len(warnings) > 0 {
+		t.Fatalf("bad: %#v", warnings)
+	}
+	if err == nil {
+		t.Fatal("should have error: 'volumes must be set to use volume_snapshots'")
+	}
+
+	// Test default name
+	config["volumes"] = []map[string]interface{}{
+		{"size": 10, "filesystem_type": "ext4"},
+	}
+	b = Builder{}
+	_, warnings, err = b.Prepare(config)
+	if len(warnings) > 0 {
+		t.Fatalf("bad: %#v", warnings)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	if b.config.VolumeSnapshotName != "{{ .SnapshotName }}-{{ .VolumeName }}" {
+		t.Errorf("invalid: %s", b.config.VolumeSnapshotName)
+	}
+
+	// Test with bad template
+	config["volume_snapshot_name"] = "{{ .VolumeName"
+	b = Builder{}
+	_, warnings, err = b.Prepare(config)
+	if len(warnings) > 0 {
+		t.Fatalf("bad: %#v", warnings)
+	}
+	if err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestBuilderPrepare_TemporaryFirewall(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	// Test with source CIDRs but no temporary firewall
+	config["temporary_firewall_source_cidrs"] = []string{"203.0.113.0/24"}
+	_, warnings, err := b.Prepare(config)
+	if len(warnings) > 0 {
+		t.Fatalf("bad: %#v", warnings)
+	}
+	if err == nil {
+		t.Fatal("should have error: 'temporary_firewall must be enabled to use temporary_firewall_source_cidrs'")
+	}
+
+	// Test with a temporary firewall
+	config["temporary_firewall"] = true
+	b = Builder{}
+	_, warnings, err = b.Prepare(config)
+	if len(warnings) > 0 {
+		t.Fatalf("bad: %#v", warnings)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	// Test with an invalid CIDR
+	config["temporary_firewall_source_cidrs"] = []string{"203.0.113.0"}
+	b = Builder{}
+	_, warnings, err = b.Prepare(config)
+	if len(warnings) > 0 {
+		t.Fatalf("bad: %#v", warnings)
+	}
+	if err == nil {
+		t.Fatal("should have error: 'invalid temporary_firewall_source_cidrs entry: 203.0.113.0'")
+	}
+
+	// Test without a communicator
+	delete(config, "temporary_firewall_source_cidrs")
+	delete(config, "ssh_username")
+	config["communicator"] = "none"
+	b = Builder{}
+	_, warnings, err = b.Prepare(config)
+	if len(warnings) > 0 {
+		t.Fatalf("bad: %#v", warnings)
+	}
+	if err == nil {
+		t.Fatal("should have error: 'temporary_firewall requires a communicator'")
+	}
+}
+
+func TestBuilderPrepare_SourceDropletID(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	// Test with image, size, and region set
+	config["source_droplet_id"] = 12345
+	config["ssh_password"] = "secret"
+	_, warnings, err := b.Prepare(config)
+	if len(warnings) > 0 {
+		t.Fatalf("bad: %#v", warnings)
+	}
+	if err == nil {
+		t.Fatal("should have error: 'region, size, and image can not be used with source_droplet_id'")
+	}
+
+	// Test without image, size, and region
+	delete(config, "image")
+	delete(config, "size")
+	delete(config, "region")
+	config["power_on_after_snapshot"] = true
+	b = Builder{}
+	_, warnings, err = b.Prepare(config)
+	if len(warnings) > 0 {
+		t.Fatalf("bad: %#v", warnings)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	// Test without any SSH credentials
+	delete(config, "ssh_password")
+	b = Builder{}
+	_, warnings, err = b.Prepare(config)
+	if len(warnings) > 0 {
+		t.Fatalf("bad: %#v", warnings)
+	}
+	if err == nil {
+		t.Fatal("should have error: 'one of ssh_private_key_file, ssh_password, or ssh_agent_auth must be set when using source_droplet_id'")
+	}
+
+	// Test without a communicator
+	config["communicator"] = "none"
+	b = Builder{}
+	_, warnings, err = b.Prepare(config)
+	if len(warnings) > 0 {
+		t.Fatalf("bad: %#v", warnings)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+}
+
+func TestBuilderPrepare_ImageFilter(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	// Test with both image and image_filter
+	config["image_filter"] = map[string]interface{}{
+		"name_regex": "^base-.*",
+		"latest":     true,
+	}
+	_, warnings, err := b.Prepare(config)
+	if len(warnings) > 0 {
+		t.Fatalf("bad: %#v", warnings)
+	}
+	if err == nil {
+		t.Fatal("should have error: 'only one of image or image_filter can be set'")
+	}
+
+	// Test with image_filter only
+	delete(config, "image")
+	b = Builder{}
+	_, warnings, err = b.Prepare(config)
+	if len(warnings) > 0 {
+		t.Fatalf("bad: %#v", warnings)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	if b.config.ImageFilter.Region != "nyc2" {
+		t.Errorf("image_filter region should default to the build region, got %s", b.config.ImageFilter.Region)
+	}
+
+	// Test with an invalid filter
+	config["image_filter"] = map[string]interface{}{
+		"type": "user",
+	}
+	b = Builder{}
+	_, warnings, err = b.Prepare(config)
+	if len(warnings) > 0 {
+		t.Fatalf("bad: %#v", warnings)
+	}
+	if err == nil {
+		t.Fatal("should have error: 'image_filter: one of name or name_regex is required'")
+	}
+}
+
+func TestBuilderPrepare_FallbackRegions(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	// Test with fallback regions and sizes
+	config["fallback_regions"] = []string{"nyc3", "sfo3"}
+	config["fallback_sizes"] = []string{"s-2vcpu-2gb"}
+	_, warnings, err := b.Prepare(config)
+	if len(warnings) > 0 {
+		t.Fatalf("bad: %#v", warnings)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	// Test with volumes
+	config["volumes"] = []map[string]interface{}{
+		{"size": 10},
+	}
+	b = Builder{}
+	_, warnings, err = b.Prepare(config)
+	if len(warnings) > 0 {
+		t.Fatalf("bad: %#v", warnings)
+	}
+	if err == nil {
+		t.Fatal("should have error: 'fallback_regions can not be used with volumes or vpc_uuid'")
+	}
+}
+
+func TestBuilderPrepare_Resume(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	// Test without any SSH credentials
+	config["resume"] = true
+	_, warnings, err := b.Prepare(config)
+	if len(warnings) > 0 {
+		t.Fatalf("bad: %#v", warnings)
+	}
+	if err == nil {
+		t.Fatal("should have error: 'one of ssh_private_key_file, ssh_password, or ssh_agent_auth must be set when using resume'")
+	}
+
+	// Test with SSH agent auth
+	config["ssh_agent_auth"] = true
+	b = Builder{}
+	_, warnings, err = b.Prepare(config)
+	if len(warnings) > 0 {
+		t.Fatalf("bad: %#v", warnings)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	// Test with volumes
+	config["volumes"] = []map[string]interface{}{
+		{"size": 10},
+	}
+	b = Builder{}
+	_, warnings, err = b.Prepare(config)
+	if len(warnings) > 0 {
+		t.Fatalf("bad: %#v", warnings)
+	}
+	if err == nil {
+		t.Fatal("should have error: 'resume can not be used with source_droplet_id or volumes'")
+	}
+}
+
+func TestBuilderPrepare_UserDataSSHKey(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	// Test with the SSH communicator
+	config["user_data_ssh_key"] = true
+	_, warnings, err := b.Prepare(config)
+	if len(warnings) > 0 {
+		t.Fatalf("bad: %#v", warnings)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	// Test with an existing SSH key
+	config["ssh_key_id"] = 1234
+	config["ssh_private_key_file"] = "/path/to/key"
+	b = Builder{}
+	_, warnings, err = b.Prepare(config)
+	if len(warnings) > 0 {
+		t.Fatalf("bad: %#v", warnings)
+	}
+	if err == nil {
+		t.Fatal("should have error: 'user_data_ssh_key can not be used with source_droplet_id or ssh_key_id'")
+	}
+}
+
+func TestBuilderPrepare_UserData(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	tf, err := os.CreateTemp("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.Remove(tf.Name())
+	tf.Close()
+
+	// Test with user_data, user_data_file, and user_data_files together
+	config["user_data"] = "#!/bin/sh\nhostname {{ .DropletName }}\n"
+	config["user_data_file"] = tf.Name()
+	config["user_data_files"] = []string{tf.Name()}
+	_, warnings, err := b.Prepare(config)
+	if len(warnings) > 0 {
+		t.Fatalf("bad: %#v", warnings)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if b.config.UserData != config["user_data"] {
+		t.Errorf("user_data should not be rendered at prepare: %s", b.config.UserData)
+	}
+
+	// Test with a missing file
+	config["user_data_files"] = []string{tf.Name(), "/i/dont/exist"}
+	b = Builder{}
+	_, warnings, err = b.Prepare(config)
+	if len(warnings) > 0 {
+		t.Fatalf("bad: %#v", warnings)
+	}
+	if err == nil {
+		t.Fatal("should have error: 'user_data_files: file not found'")
+	}
+
+	// Test with an invalid template
+	delete(config, "user_data_files")
+	config["user_data"] = "{{ .DropletName"
+	b = Builder{}
+	_, warnings, err = b.Prepare(config)
+	if len(warnings) > 0 {
+		t.Fatalf("bad: %#v", warnings)
+	}
+	if err == nil {
+		t.Fatal("should have error: 'Error parsing user_data template'")
+	}
+}
+
+func TestBuilderPrepare_WaitForCloudInit(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	// Test default
+	config["wait_for_cloud_init"] = true
+	_, warnings, err := b.Prepare(config)
+	if len(warnings) > 0 {
+		t.Fatalf("bad: %#v", warnings)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if b.config.CloudInitTimeout != 30*time.Minute {
+		t.Errorf("invalid: %s", b.config.CloudInitTimeout)
+	}
+
+	// Test with the none communicator
+	config["communicator"] = "none"
+	b = Builder{}
+	_, warnings, err = b.Prepare(config)
+	if len(warnings) > 0 {
+		t.Fatalf("bad: %#v", warnings)
+	}
+	if err == nil {
+		t.Fatal("should have error: 'wait_for_cloud_init can only be used with the ssh communicator'")
+	}
+}
+
+func TestBuilderPrepare_WinRMUserDataPassword(t *testing.T) {
+	var b Builder
+	config := testConfig()
+	delete(config, "ssh_username")
+
+	// Test with the winrm communicator
+	config["communicator"] = "winrm"
+	config["winrm_user_data_password"] = true
+	config["winrm_username"] = "packer"
+	config["winrm_password"] = "Secret-1"
+	_, warnings, err := b.Prepare(config)
+	if len(warnings) > 0 {
+		t.Fatalf("bad: %#v", warnings)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if b.config.Comm.WinRMUser != "packer" || b.config.Comm.WinRMPassword != "Secret-1" {
+		t.Errorf("invalid: %s/%s", b.config.Comm.WinRMUser, b.config.Comm.WinRMPassword)
+	}
+
+	// Test resume with a generated password
+	delete(config, "winrm_password")
+	config["resume"] = true
+	b = Builder{}
+	_, warnings, err = b.Prepare(config)
+	if len(warnings) > 0 {
+		t.Fatalf("bad: %#v", warnings)
+	}
+	if err == nil {
+		t.Fatal("should have error: 'winrm_password must be set when using resume with winrm_user_data_password'")
+	}
+
+	// Test with the ssh communicator
+	delete(config, "resume")
+	config["communicator"] = "ssh"
+	config["ssh_username"] = "root"
+	b = Builder{}
+	_, warnings, err = b.Prepare(config)
+	if len(warnings) > 0 {
+		t.Fatalf("bad: %#v", warnings)
+	}
+	if err == nil {
+		t.Fatal("should have error: 'winrm_user_data_password can only be used with the winrm communicator'")
+	}
+}
+
+func TestBuilderRun_SourceDropletOff(t *testing.T) {
+	var actions []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v2/regions", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"regions":[{"slug":"nyc3","available":true}]}`))
+	})
+	mux.HandleFunc("/v2/droplets/1234", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"droplet":{"id":1234,"name":"golden","status":"off","region":{"slug":"nyc3"}}}`))
+	})
+	mux.HandleFunc("/v2/droplets/1234/actions", func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		actions = append(actions, req["type"].(string))
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"action":{"id":5,"status":"in-progress"}}`))
+	})
+	mux.HandleFunc("/v2/droplets/1234/actions/5", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"action":{"id":5,"status":"completed"}}`))
+	})
+	mux.HandleFunc("/v2/droplets/1234/snapshots", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"snapshots":[{"id":99,"name":"golden-snapshot"}]}`))
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request: %s %s", r.Method, r.URL)
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	var b Builder
+	_, _, err := b.Prepare(map[string]interface{}{
+		"api_token":         "bar",
+		"api_url":           server.URL,
+		"source_droplet_id": 1234,
+		"communicator":      "none",
+		"snapshot_name":     "golden-snapshot",
+		"state_timeout":     "2s",
+		"poll_interval":     "10ms",
+	})
+	require.NoError(t, err)
+
+	artifact, err := b.Run(context.Background(), packersdk.TestUi(t), &packersdk.MockHook{})
+	require.NoError(t, err)
+	require.Equal(t, "nyc3:99", artifact.Id())
+	// The droplet is neither powered on nor shut down
+	require.Equal(t, []string{"snapshot"}, actions)
+}
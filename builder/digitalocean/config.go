@@ -1,17 +1,18 @@
 //go:generate packer-sdc struct-markdown
-//go:generate packer-sdc mapstructure-to-hcl2 -type Config
+//go:generate packer-sdc mapstructure-to-hcl2 -type Config,VolumeConfig
 
 package digitalocean
 
 import (
 	"errors"
 	"fmt"
+	"net"
 	"os"
 	"regexp"
-	"strconv"
 	"time"
 
 	"github.com/digitalocean/godo"
+	docommon "github.com/digitalocean/packer-plugin-digitalocean/common"
 	"github.com/hashicorp/packer-plugin-sdk/common"
 	"github.com/hashicorp/packer-plugin-sdk/communicator"
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
@@ -22,23 +23,9 @@ import (
 )
 
 type Config struct {
-	common.PackerConfig `mapstructure:",squash"`
-	Comm                communicator.Config `mapstructure:",squash"`
-	// The client TOKEN to use to access your account. It
-	// can also be specified via environment variable DIGITALOCEAN_TOKEN, DIGITALOCEAN_ACCESS_TOKEN, or DIGITALOCEAN_API_TOKEN if
-	// set. DIGITALOCEAN_API_TOKEN will be deprecated in a future release in favor of DIGITALOCEAN_TOKEN or DIGITALOCEAN_ACCESS_TOKEN.
-	APIToken string `mapstructure:"api_token" required:"true"`
-	// Non standard api endpoint URL. Set this if you are
-	// using a DigitalOcean API compatible service. It can also be specified via
-	// environment variable DIGITALOCEAN_API_URL.
-	APIURL string `mapstructure:"api_url" required:"false"`
-	// The maximum number of retries for requests that fail with a 429 or 500-level error.
-	// The default value is 5. Set to 0 to disable reties.
-	HTTPRetryMax *int `mapstructure:"http_retry_max" required:"false"`
-	// The maximum wait time (in seconds) between failed API requests. Default: 30.0
-	HTTPRetryWaitMax *float64 `mapstructure:"http_retry_wait_max" required:"false"`
-	// The minimum wait time (in seconds) between failed API requests. Default: 1.0
-	HTTPRetryWaitMin *float64 `mapstructure:"http_retry_wait_min" required:"false"`
+	common.PackerConfig   `mapstructure:",squash"`
+	docommon.AccessConfig `mapstructure:",squash"`
+	Comm                  communicator.Config `mapstructure:",squash"`
 	// The name (or slug) of the region to launch the droplet
 	// in. Consequently, this is the region where the snapshot will be available.
 	// See
@@ -49,11 +36,37 @@ type Config struct {
 	// https://docs.digitalocean.com/reference/api/api-reference/#operation/list_all_sizes
 	// for the accepted size names/slugs.
 	Size string `mapstructure:"size" required:"true"`
+	// Regions to try, in order, when the droplet can not be created in
+	// `region` because of a capacity error or because the size is not
+	// available there. The region actually used is recorded as the
+	// `build_region` of the artifact and is where the snapshot is taken
+	// before being distributed to `snapshot_regions`. Can not be used with
+	// `volumes` or `vpc_uuid`, which are tied to a region.
+	FallbackRegions []string `mapstructure:"fallback_regions" required:"false"`
+	// Sizes to try, in order, when the droplet can not be created with
+	// `size` because of a capacity error or because the size is not
+	// available in the region. Every size is tried in a region before moving
+	// on to the next fallback region.
+	FallbackSizes []string `mapstructure:"fallback_sizes" required:"false"`
 	// The name (or slug) of the base image to use. This is the
 	// image that will be used to launch a new droplet and provision it. See
 	// https://docs.digitalocean.com/reference/api/api-reference/#operation/get_images_list
 	// for details on how to get a list of the accepted image names/slugs.
 	Image string `mapstructure:"image" required:"true"`
+	// Filters used to look up the base image when building, instead of
+	// setting `image`. The filter must match a single image unless `latest` is
+	// set, and `region` defaults to the build region. The ID of the image
+	// found is recorded as the `source_image_id` of the artifact. Only one of
+	// `image` or `image_filter` may be provided.
+	//
+	// ```hcl
+	// image_filter {
+	//   name_regex = "^base-.*"
+	//   type       = "user"
+	//   latest     = true
+	// }
+	// ```
+	ImageFilter *docommon.ImageFilterOptions `mapstructure:"image_filter" required:"false"`
 	// Set to true to enable private networking
 	// for the droplet being created. This defaults to false, or not enabled.
 	PrivateNetworking bool `mapstructure:"private_networking" required:"false"`
@@ -91,17 +104,41 @@ type Config struct {
 	// The default snapshot timeout is "60m" (valid time units include `s` for
 	// seconds, `m` for minutes, and `h` for hours).
 	SnapshotTimeout time.Duration `mapstructure:"snapshot_timeout" required:"false"`
+	// The initial time to wait between two checks of a droplet, action, or
+	// snapshot transfer state. The delay doubles after each check, up to 30
+	// seconds. The default poll interval is "3s".
+	PollInterval time.Duration `mapstructure:"poll_interval" required:"false"`
 	// The name assigned to the droplet. DigitalOcean
 	// sets the hostname of the machine to this value.
 	DropletName string `mapstructure:"droplet_name" required:"false"`
 	// User data to launch with the Droplet. Packer will
 	// not automatically wait for a user script to finish before shutting down the
-	// instance this must be handled in a provisioner.
+	// instance, unless `wait_for_cloud_init` is set. This is a template
+	// engine where the `DropletName` and `BuildName` variables are
+	// available.
 	UserData string `mapstructure:"user_data" required:"false"`
 	// Path to a file that will be used for the user
 	// data when launching the Droplet.
 	UserDataFile string `mapstructure:"user_data_file" required:"false"`
-	// Tags to apply to the droplet when it is created
+	// Paths to files with more user data, such as cloud-config documents or
+	// shell scripts. When more than one of `user_data_file`,
+	// `user_data_files`, and `user_data` is set, they are combined, in that
+	// order, in a MIME multi-part document. The content type of each part is
+	// detected from its first line, such as `#cloud-config` or `#!`. Files
+	// are not rendered as templates.
+	UserDataFiles []string `mapstructure:"user_data_files" required:"false"`
+	// When true, Packer waits for cloud-init to finish processing the user
+	// data after connecting to the droplet and before running the
+	// provisioners, and fails the build if cloud-init reports errors.
+	// Defaults to `false`.
+	WaitForCloudInit bool `mapstructure:"wait_for_cloud_init" required:"false"`
+	// How long to wait for cloud-init to finish when `wait_for_cloud_init`
+	// is set. The default cloud-init timeout is "30m".
+	CloudInitTimeout time.Duration `mapstructure:"cloud_init_timeout" required:"false"`
+	// Tags to apply to the droplet when it is created. Temporary droplets and
+	// volumes are also tagged with `packer-build`, so that the ones left over
+	// from crashed builds can be removed with the `digitalocean-cleanup`
+	// post-processor.
 	Tags []string `mapstructure:"tags" required:"false"`
 	// Tags to apply to the snapshot after it is created
 	SnapshotTags []string `mapstructure:"snapshot_tags" required:"false"`
@@ -116,10 +153,104 @@ type Config struct {
 	// The ID of an existing SSH key on the DigitalOcean account. This should be
 	// used in conjunction with `ssh_private_key_file`.
 	SSHKeyID int `mapstructure:"ssh_key_id" required:"false"`
+	// When true, the temporary SSH public key is added to the droplet through
+	// cloud-init user data instead of being imported as an SSH key of the
+	// account, so the API token does not need write access to account SSH
+	// keys. The key is combined with `user_data` or `user_data_file` in a
+	// MIME multi-part document. The image must run cloud-init. Can not be
+	// used with `ssh_key_id`. Defaults to false.
+	UserDataSSHKey bool `mapstructure:"user_data_ssh_key" required:"false"`
+	// When true, a PowerShell script is added to the user data of Windows
+	// droplets that sets the password of `winrm_username` to
+	// `winrm_password` and enables WinRM with basic authentication, over
+	// HTTPS with a self-signed certificate when `winrm_use_ssl` is set. The
+	// custom image must run cloudbase-init. `winrm_username` defaults to
+	// `Administrator` and a random `winrm_password` is generated when not
+	// set. The password can be read from the droplet metadata, so it should
+	// be changed by a provisioner. Requires the `winrm` communicator.
+	// Defaults to false.
+	WinRMUserDataPassword bool `mapstructure:"winrm_user_data_password" required:"false"`
+	// The ID of an existing droplet to snapshot instead of creating a new
+	// one. `image`, `size`, and `region` must not be set, as they are taken
+	// from the droplet. No temporary SSH key is imported, so provisioners
+	// need `ssh_private_key_file`, `ssh_password`, or `ssh_agent_auth` to
+	// connect; set `communicator` to `none` to only take the snapshot. The
+	// droplet is shut down for the snapshot and is never destroyed by Packer.
+	SourceDropletID int `mapstructure:"source_droplet_id" required:"false"`
+	// When true, the droplet set in `source_droplet_id` is powered back on
+	// once the build is done. Defaults to false.
+	PowerOnAfterSnapshot bool `mapstructure:"power_on_after_snapshot" required:"false"`
+	// When true, the droplet is tagged with a fingerprint of the build name
+	// and droplet settings, and is kept when the build fails or is
+	// cancelled. The next run of the same build reuses the droplet if it is
+	// still active, running the provisioners again on it, and destroys it
+	// otherwise. Provisioners must be safe to run more than once. The
+	// communicator must use `ssh_private_key_file`, `ssh_password`, or
+	// `ssh_agent_auth`, as a temporary key would not be installed on the
+	// resumed droplet. Can not be used with `source_droplet_id` or `volumes`.
+	// Defaults to false.
+	Resume bool `mapstructure:"resume" required:"false"`
+	// When true, Packer checks the configuration and looks up the regions,
+	// sizes, image, VPC, and existing snapshots with the same name, then
+	// prints the droplet create request, the snapshot regions, and the tags
+	// of the build, and stops without creating anything. No artifact is
+	// produced. Defaults to false.
+	DryRun bool `mapstructure:"dry_run" required:"false"`
+	// Block storage volumes to attach to the droplet while it is being
+	// provisioned. Volumes are created in the build region and attached
+	// before Packer connects to the droplet. Temporary volumes are deleted
+	// once the build finishes. See [Volumes](#volumes) for the available
+	// options.
+	Volumes []VolumeConfig `mapstructure:"volumes" required:"false"`
+	// When true, Packer takes a snapshot of every volume in `volumes` after
+	// the droplet snapshot is created. The volume snapshots are tagged with
+	// `snapshot_tags` and their IDs are recorded in the artifact. Defaults to
+	// false.
+	VolumeSnapshots bool `mapstructure:"volume_snapshots" required:"false"`
+	// The name of the volume snapshots. This is a template engine where the
+	// `SnapshotName` and `VolumeName` variables are available. Defaults to
+	// `{{ .SnapshotName }}-{{ .VolumeName }}`.
+	VolumeSnapshotName string `mapstructure:"volume_snapshot_name" required:"false"`
+
+	// When true, Packer creates a temporary DigitalOcean Cloud Firewall that
+	// only allows connections to the communicator port from the public IP of
+	// the machine running Packer, or from `temporary_firewall_source_cidrs`
+	// when set. The firewall is applied to the droplet through a temporary tag
+	// and is deleted at the end of the build. Requires a communicator.
+	// Defaults to false.
+	TemporaryFirewall bool `mapstructure:"temporary_firewall" required:"false"`
+	// A list of IPv4 or IPv6 CIDR blocks allowed to reach the communicator
+	// port through the temporary firewall, such as `["203.0.113.0/24"]`. When
+	// not set, the public IP of the machine running Packer is detected and
+	// used instead.
+	TemporaryFirewallSourceCIDRs []string `mapstructure:"temporary_firewall_source_cidrs" required:"false"`
 
 	ctx interpolate.Context
 }
 
+// VolumeConfig describes a block storage volume attached to the build
+// droplet. Volumes formatted with a `filesystem_type` are mounted at
+// `/mnt/<name>` (with dashes replaced by underscores) on images that support
+// automatic mounting. The mount paths are available to provisioners as the
+// comma separated `VolumeMountPaths` build variable.
+type VolumeConfig struct {
+	// The name of the volume to create. Must be lowercase, start with a
+	// letter, and only contain letters, numbers, and dashes. Defaults to
+	// `packer-volume-[time-ordered-uuid]`.
+	Name string `mapstructure:"name" required:"false"`
+	// The size of the volume to create, in GiB. Required unless `volume_id`
+	// is set.
+	Size int64 `mapstructure:"size" required:"false"`
+	// The filesystem to format the volume with. This may be `ext4` or `xfs`.
+	// When not set, the volume is left unformatted and is not mounted.
+	FilesystemType string `mapstructure:"filesystem_type" required:"false"`
+	// The ID of an existing volume to attach instead of creating a new one.
+	// The volume must be in the build region and not attached to another
+	// droplet. Existing volumes are detached, but never deleted, at the end
+	// of the build.
+	VolumeID string `mapstructure:"volume_id" required:"false"`
+}
+
 func (c *Config) Prepare(raws ...interface{}) ([]string, error) {
 
 	// Accumulate warnings and errors
@@ -134,6 +265,8 @@ func (c *Config) Prepare(raws ...interface{}) ([]string, error) {
 		InterpolateFilter: &interpolate.RenderFilter{
 			Exclude: []string{
 				"run_command",
+				"volume_snapshot_name",
+				"user_data",
 			},
 		},
 	}, raws...)
@@ -142,53 +275,10 @@ func (c *Config) Prepare(raws ...interface{}) ([]string, error) {
 	}
 
 	// Defaults
-	if c.APIToken == "" {
-		// Default to environment variable for api_token, if it exists
-		c.APIToken = os.Getenv("DIGITALOCEAN_TOKEN")
-		if c.APIToken == "" {
-			c.APIToken = os.Getenv("DIGITALOCEAN_ACCESS_TOKEN")
-		}
-		if c.APIToken == "" {
-			c.APIToken = os.Getenv("DIGITALOCEAN_API_TOKEN")
-			if c.APIToken != "" {
-				warns = append(warns, "The DIGITALOCEAN_API_TOKEN environment variable is deprecated "+
-					"and will produce an error in future versions of the DigitalOcean Packer plugin. "+
-					"Please use either DIGITALOCEAN_TOKEN or DIGITALOCEAN_ACCESS_TOKEN moving forward.")
-			}
-		}
-	}
-	if c.APIURL == "" {
-		c.APIURL = os.Getenv("DIGITALOCEAN_API_URL")
-	}
-	if c.HTTPRetryMax == nil {
-		c.HTTPRetryMax = godo.PtrTo(5)
-		if max := os.Getenv("DIGITALOCEAN_HTTP_RETRY_MAX"); max != "" {
-			maxInt, err := strconv.Atoi(max)
-			if err != nil {
-				return nil, err
-			}
-			c.HTTPRetryMax = godo.PtrTo(maxInt)
-		}
-	}
-	if c.HTTPRetryWaitMax == nil {
-		c.HTTPRetryWaitMax = godo.PtrTo(30.0)
-		if waitMax := os.Getenv("DIGITALOCEAN_HTTP_RETRY_WAIT_MAX"); waitMax != "" {
-			waitMaxFloat, err := strconv.ParseFloat(waitMax, 64)
-			if err != nil {
-				return nil, err
-			}
-			c.HTTPRetryWaitMax = godo.PtrTo(waitMaxFloat)
-		}
-	}
-	if c.HTTPRetryWaitMin == nil {
-		c.HTTPRetryWaitMin = godo.PtrTo(1.0)
-		if waitMin := os.Getenv("DIGITALOCEAN_HTTP_RETRY_WAIT_MIN"); waitMin != "" {
-			waitMinFloat, err := strconv.ParseFloat(waitMin, 64)
-			if err != nil {
-				return nil, err
-			}
-			c.HTTPRetryWaitMin = godo.PtrTo(waitMinFloat)
-		}
+	accessWarns, accessErrs := c.AccessConfig.Prepare()
+	warns = append(warns, accessWarns...)
+	if len(accessErrs) > 0 {
+		errs = packersdk.MultiErrorAppend(errs, accessErrs...)
 	}
 
 	if c.SnapshotName == "" {
@@ -201,6 +291,10 @@ func (c *Config) Prepare(raws ...interface{}) ([]string, error) {
 		c.SnapshotName = def
 	}
 
+	if c.VolumeSnapshotName == "" {
+		c.VolumeSnapshotName = "{{ .SnapshotName }}-{{ .VolumeName }}"
+	}
+
 	if c.DropletName == "" {
 		// Default to packer-[time-ordered-uuid]
 		c.DropletName = fmt.Sprintf("packer-%s", uuid.TimeOrderedUUID())
@@ -217,47 +311,118 @@ func (c *Config) Prepare(raws ...interface{}) ([]string, error) {
 		c.SnapshotTimeout = 60 * time.Minute
 	}
 
+	if c.PollInterval == 0 {
+		c.PollInterval = docommon.DefaultPollInterval
+	}
+
 	if c.TransferTimeout == 0 {
 		c.TransferTimeout = 30 * time.Minute
 	}
 
+	if c.CloudInitTimeout == 0 {
+		c.CloudInitTimeout = 30 * time.Minute
+	}
+
 	if c.WaitSnapshotTransfer == nil {
 		c.WaitSnapshotTransfer = godo.PtrTo(true)
 	}
 
+	if c.WinRMUserDataPassword && c.Comm.Type == "winrm" {
+		if c.Resume && c.Comm.WinRMPassword == "" {
+			// A generated password would not match the one of the
+			// resumed droplet
+			errs = packersdk.MultiErrorAppend(
+				errs, errors.New("winrm_password must be set when using resume with winrm_user_data_password"))
+		}
+
+		if c.Comm.WinRMUser == "" {
+			c.Comm.WinRMUser = "Administrator"
+		}
+
+		if c.Comm.WinRMPassword == "" {
+			password, err := generateWinRMPassword()
+			if err != nil {
+				errs = packersdk.MultiErrorAppend(
+					errs, fmt.Errorf("Error generating winrm_password: %s", err))
+			}
+			c.Comm.WinRMPassword = password
+		}
+		packersdk.LogSecretFilter.Set(c.Comm.WinRMPassword)
+	}
+
 	if es := c.Comm.Prepare(&c.ctx); len(es) > 0 {
 		errs = packersdk.MultiErrorAppend(errs, es...)
 	}
-	if c.APIToken == "" {
-		// Required configurations that will display errors if not set
-		errs = packersdk.MultiErrorAppend(
-			errs, errors.New("api_token for auth must be specified"))
-	}
 
-	if c.Region == "" {
-		errs = packersdk.MultiErrorAppend(
-			errs, errors.New("region is required"))
-	}
+	if c.SourceDropletID == 0 {
+		if c.Region == "" {
+			errs = packersdk.MultiErrorAppend(
+				errs, errors.New("region is required"))
+		}
 
-	if c.Size == "" {
-		errs = packersdk.MultiErrorAppend(
-			errs, errors.New("size is required"))
-	}
+		if c.Size == "" {
+			errs = packersdk.MultiErrorAppend(
+				errs, errors.New("size is required"))
+		}
 
-	if c.Image == "" {
-		errs = packersdk.MultiErrorAppend(
-			errs, errors.New("image is required"))
+		if c.Image == "" && c.ImageFilter == nil {
+			errs = packersdk.MultiErrorAppend(
+				errs, errors.New("image is required"))
+		}
+
+		if c.Image != "" && c.ImageFilter != nil {
+			errs = packersdk.MultiErrorAppend(
+				errs, errors.New("only one of image or image_filter can be set"))
+		}
+
+		if c.ImageFilter != nil {
+			if c.ImageFilter.Region == "" {
+				c.ImageFilter.Region = c.Region
+			}
+			for _, err := range c.ImageFilter.Prepare() {
+				errs = packersdk.MultiErrorAppend(
+					errs, fmt.Errorf("image_filter: %s", err))
+			}
+		}
+
+		if c.PowerOnAfterSnapshot {
+			errs = packersdk.MultiErrorAppend(
+				errs, errors.New("source_droplet_id must be set to use power_on_after_snapshot"))
+		}
+	} else {
+		if c.Region != "" || c.Size != "" || c.Image != "" || c.ImageFilter != nil ||
+			len(c.FallbackRegions) > 0 || len(c.FallbackSizes) > 0 {
+			errs = packersdk.MultiErrorAppend(
+				errs, errors.New("region, size, image, image_filter, fallback_regions, and fallback_sizes can not be used with source_droplet_id"))
+		}
+
+		if len(c.Volumes) > 0 || c.TemporaryFirewall || c.UserData != "" || c.UserDataFile != "" || len(c.UserDataFiles) > 0 {
+			errs = packersdk.MultiErrorAppend(
+				errs, errors.New("volumes, temporary_firewall, user_data, user_data_file, and user_data_files can not be used with source_droplet_id"))
+		}
+
+		if c.Comm.Type == "ssh" && c.Comm.SSHPrivateKeyFile == "" && c.Comm.SSHPassword == "" && !c.Comm.SSHAgentAuth {
+			errs = packersdk.MultiErrorAppend(
+				errs, errors.New("one of ssh_private_key_file, ssh_password, or ssh_agent_auth must be set when using source_droplet_id"))
+		}
 	}
 
-	if c.UserData != "" && c.UserDataFile != "" {
-		errs = packersdk.MultiErrorAppend(
-			errs, errors.New("only one of user_data or user_data_file can be specified"))
-	} else if c.UserDataFile != "" {
+	if c.UserDataFile != "" {
 		if _, err := os.Stat(c.UserDataFile); err != nil {
 			errs = packersdk.MultiErrorAppend(
 				errs, fmt.Errorf("user_data_file not found: %s", c.UserDataFile))
 		}
 	}
+	for _, file := range c.UserDataFiles {
+		if _, err := os.Stat(file); err != nil {
+			errs = packersdk.MultiErrorAppend(
+				errs, fmt.Errorf("user_data_files: file not found: %s", file))
+		}
+	}
+	if err := interpolate.Validate(c.UserData, &c.ctx); err != nil {
+		errs = packersdk.MultiErrorAppend(
+			errs, fmt.Errorf("Error parsing user_data template: %s", err))
+	}
 
 	if c.Tags == nil {
 		c.Tags = make([]string, 0)
@@ -270,6 +435,95 @@ func (c *Config) Prepare(raws ...interface{}) ([]string, error) {
 		}
 	}
 
+	volumeNameRe := regexp.MustCompile("^[a-z][a-z0-9-]{0,63}$")
+	for i := range c.Volumes {
+		v := &c.Volumes[i]
+		if v.VolumeID != "" {
+			if v.Size != 0 || v.Name != "" || v.FilesystemType != "" {
+				errs = packersdk.MultiErrorAppend(errs, fmt.Errorf(
+					"volumes[%d]: name, size, and filesystem_type can not be used with volume_id", i))
+			}
+			continue
+		}
+
+		if v.Name == "" {
+			// Default to packer-volume-[time-ordered-uuid]
+			v.Name = fmt.Sprintf("packer-volume-%s", uuid.TimeOrderedUUID())
+		}
+		if !volumeNameRe.MatchString(v.Name) {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("volumes[%d]: invalid name: %s", i, v.Name))
+		}
+		if v.Size <= 0 {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("volumes[%d]: size is required", i))
+		}
+		switch v.FilesystemType {
+		case "", "ext4", "xfs":
+		default:
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf(
+				"volumes[%d]: filesystem_type must be one of ext4 or xfs", i))
+		}
+	}
+
+	if c.VolumeSnapshots {
+		if len(c.Volumes) == 0 {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("volumes must be set to use volume_snapshots"))
+		}
+		if err := interpolate.Validate(c.VolumeSnapshotName, &c.ctx); err != nil {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("Error parsing volume_snapshot_name template: %s", err))
+		}
+	}
+
+	if c.TemporaryFirewall && c.Comm.Type == "none" {
+		errs = packersdk.MultiErrorAppend(errs, errors.New("temporary_firewall requires a communicator"))
+	}
+	if len(c.TemporaryFirewallSourceCIDRs) > 0 && !c.TemporaryFirewall {
+		errs = packersdk.MultiErrorAppend(errs, errors.New("temporary_firewall must be enabled to use temporary_firewall_source_cidrs"))
+	}
+	for _, cidr := range c.TemporaryFirewallSourceCIDRs {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("invalid temporary_firewall_source_cidrs entry: %s", cidr))
+		}
+	}
+
+	if c.UserDataSSHKey {
+		if c.SourceDropletID != 0 || c.SSHKeyID != 0 {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("user_data_ssh_key can not be used with source_droplet_id or ssh_key_id"))
+		}
+
+		if c.Comm.Type != "ssh" {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("user_data_ssh_key can only be used with the ssh communicator"))
+		}
+	}
+
+	if c.WinRMUserDataPassword {
+		if c.SourceDropletID != 0 {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("winrm_user_data_password can not be used with source_droplet_id"))
+		}
+
+		if c.Comm.Type != "winrm" {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("winrm_user_data_password can only be used with the winrm communicator"))
+		}
+	}
+
+	if c.WaitForCloudInit && c.Comm.Type != "ssh" {
+		errs = packersdk.MultiErrorAppend(errs, errors.New("wait_for_cloud_init can only be used with the ssh communicator"))
+	}
+
+	if c.Resume {
+		if c.SourceDropletID != 0 || len(c.Volumes) > 0 {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("resume can not be used with source_droplet_id or volumes"))
+		}
+
+		if c.Comm.Type == "ssh" && c.Comm.SSHPrivateKeyFile == "" && c.Comm.SSHPassword == "" && !c.Comm.SSHAgentAuth {
+			errs = packersdk.MultiErrorAppend(
+				errs, errors.New("one of ssh_private_key_file, ssh_password, or ssh_agent_auth must be set when using resume"))
+		}
+	}
+
+	if len(c.FallbackRegions) > 0 && (len(c.Volumes) > 0 || c.VPCUUID != "") {
+		errs = packersdk.MultiErrorAppend(errs, errors.New("fallback_regions can not be used with volumes or vpc_uuid"))
+	}
+
 	// Check if the PrivateNetworking is enabled by user before use VPC UUID
 	if c.VPCUUID != "" {
 		if !c.PrivateNetworking {
@@ -288,6 +542,5 @@ func (c *Config) Prepare(raws ...interface{}) ([]string, error) {
 		return warns, errs
 	}
 
-	packersdk.LogSecretFilter.Set(c.APIToken)
 	return warns, nil
 }
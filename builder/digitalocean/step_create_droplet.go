@@ -2,15 +2,19 @@ package digitalocean
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
 	"strconv"
+	"strings"
 
 	"io/ioutil"
 
 	"github.com/digitalocean/godo"
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
 )
 
 type stepCreateDroplet struct {
@@ -22,12 +26,17 @@ func (s *stepCreateDroplet) Run(ctx context.Context, state multistep.StateBag) m
 	ui := state.Get("ui").(packersdk.Ui)
 	c := state.Get("config").(*Config)
 
+	// A droplet from an interrupted run is being resumed
+	if _, ok := state.GetOk("droplet_id"); ok {
+		return multistep.ActionContinue
+	}
+
 	// Store the source image ID and
 	// other miscellaneous info for HCP Packer
-	state.Put("source_image_id", c.Image)
-	state.Put("droplet_size", c.Size)
+	if _, ok := state.GetOk("source_image_id"); !ok {
+		state.Put("source_image_id", c.Image)
+	}
 	state.Put("droplet_name", c.DropletName)
-	state.Put("build_region", c.Region)
 
 	// Create the droplet based on configuration
 	ui.Say("Creating droplet...")
@@ -37,9 +46,9 @@ func (s *stepCreateDroplet) Run(ctx context.Context, state multistep.StateBag) m
 		return multistep.ActionHalt
 	}
 
-	log.Printf("[DEBUG] Droplet create parameters: %s", godo.Stringify(dropletCreateReq))
-
-	droplet, _, err := client.Droplets.Create(context.TODO(), dropletCreateReq)
+	droplet, err := s.createDroplet(ctx, client, ui, dropletCreateReq,
+		append([]string{c.Region}, c.FallbackRegions...),
+		append([]string{c.Size}, c.FallbackSizes...))
 	if err != nil {
 		err := fmt.Errorf("Error creating droplet: %s", err)
 		state.Put("error", err)
@@ -47,6 +56,10 @@ func (s *stepCreateDroplet) Run(ctx context.Context, state multistep.StateBag) m
 		return multistep.ActionHalt
 	}
 
+	// The droplet may have been created with a fallback region or size
+	state.Put("droplet_size", dropletCreateReq.Size)
+	state.Put("build_region", dropletCreateReq.Region)
+
 	// We use this in cleanup
 	s.dropletId = droplet.ID
 
@@ -59,6 +72,74 @@ func (s *stepCreateDroplet) Run(ctx context.Context, state multistep.StateBag) m
 	return multistep.ActionContinue
 }
 
+// createDroplet tries to create the droplet in each region with each size,
+// in order, moving on to the next candidate only on capacity errors. The
+// request is updated with the region and size that were used.
+func (s *stepCreateDroplet) createDroplet(
+	ctx context.Context, client *godo.Client, ui packersdk.Ui,
+	req *godo.DropletCreateRequest, regions, sizes []string) (*godo.Droplet, error) {
+	var err error
+	for _, region := range regions {
+		for _, size := range sizes {
+			req.Region = region
+			req.Size = size
+
+			log.Printf("[DEBUG] Droplet create parameters: %s", godo.Stringify(req))
+
+			var droplet *godo.Droplet
+			droplet, _, err = client.Droplets.Create(ctx, req)
+			if err == nil {
+				return droplet, nil
+			}
+			if !isCapacityError(err) {
+				return nil, err
+			}
+
+			ui.Message(fmt.Sprintf("Unable to create a %s droplet in %s: %s", size, region, err))
+		}
+	}
+
+	return nil, err
+}
+
+// capacityErrorMessages are the parts of the API error messages returned
+// when a region is out of capacity or a size can not be created in it.
+var capacityErrorMessages = []string{
+	"capacity",
+	"size is not available",
+	"size is unavailable",
+	"size is currently unavailable",
+	"region is not available",
+	"region is unavailable",
+	"region is currently unavailable",
+}
+
+// isCapacityError reports whether the droplet could not be created because
+// the region is out of capacity or the size is not available there.
+func isCapacityError(err error) bool {
+	var errResp *godo.ErrorResponse
+	if !errors.As(err, &errResp) || errResp.Response == nil {
+		return false
+	}
+
+	switch errResp.Response.StatusCode {
+	case http.StatusUnprocessableEntity, http.StatusServiceUnavailable:
+	default:
+		return false
+	}
+
+	// Other validation errors, such as an image not available in the
+	// region, would fail in the fallback regions too
+	message := strings.ToLower(errResp.Message)
+	for _, s := range capacityErrorMessages {
+		if strings.Contains(message, s) {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (s *stepCreateDroplet) buildDropletCreateRequest(state multistep.StateBag) (*godo.DropletCreateRequest, error) {
 	c := state.Get("config").(*Config)
 
@@ -75,17 +156,36 @@ func (s *stepCreateDroplet) buildDropletCreateRequest(state multistep.StateBag)
 		})
 	}
 
-	userData := c.UserData
-	if c.UserDataFile != "" {
-		contents, err := ioutil.ReadFile(c.UserDataFile)
-		if err != nil {
-			return nil, fmt.Errorf("Problem reading user data file: %s", err)
+	var volumes []godo.DropletCreateVolume
+	if volumeIds, ok := state.GetOk("volume_ids"); ok {
+		for _, id := range volumeIds.([]string) {
+			volumes = append(volumes, godo.DropletCreateVolume{ID: id})
 		}
+	}
 
-		userData = string(contents)
+	userData, err := s.buildUserData(c)
+	if err != nil {
+		return nil, err
 	}
 
-	createImage := getImageType(c.Image)
+	tags := c.Tags
+	if firewallTag, ok := state.GetOk("firewall_tag"); ok {
+		tags = append(append([]string{}, tags...), firewallTag.(string))
+	}
+	if resumeTag, ok := state.GetOk("resume_tag"); ok {
+		tags = append(append([]string{}, tags...), resumeTag.(string))
+	}
+	if buildTags, ok := state.GetOk("build_tags"); ok {
+		tags = append(append([]string{}, tags...), buildTags.([]string)...)
+	}
+
+	// The image may have been resolved from image_filter
+	image := c.Image
+	if sourceImage, ok := state.GetOk("source_image_id"); ok {
+		image = sourceImage.(string)
+	}
+
+	createImage := getImageType(image)
 
 	return &godo.DropletCreateRequest{
 		Name:              c.DropletName,
@@ -93,16 +193,72 @@ func (s *stepCreateDroplet) buildDropletCreateRequest(state multistep.StateBag)
 		Size:              c.Size,
 		Image:             createImage,
 		SSHKeys:           sshKeys,
+		Volumes:           volumes,
 		PrivateNetworking: c.PrivateNetworking,
 		Monitoring:        c.Monitoring,
 		WithDropletAgent:  c.DropletAgent,
 		IPv6:              c.IPv6,
 		UserData:          userData,
-		Tags:              c.Tags,
+		Tags:              tags,
 		VPCUUID:           c.VPCUUID,
 	}, nil
 }
 
+// buildUserData renders user_data and combines it with the contents of
+// user_data_file and user_data_files, with the SSH key when
+// user_data_ssh_key is set, and with the WinRM password script when
+// winrm_user_data_password is set.
+func (s *stepCreateDroplet) buildUserData(c *Config) (string, error) {
+	var parts []userDataPart
+
+	// Enable WinRM first, so that it does not depend on the other scripts
+	if c.WinRMUserDataPassword {
+		script, err := winrmPasswordUserData(c.Comm.WinRMUser, c.Comm.WinRMPassword, c.Comm.WinRMPort, c.Comm.WinRMUseSSL)
+		if err != nil {
+			return "", fmt.Errorf("Problem rendering the WinRM password script: %s", err)
+		}
+
+		parts = append(parts, newUserDataPart(script))
+	}
+
+	files := c.UserDataFiles
+	if c.UserDataFile != "" {
+		files = append([]string{c.UserDataFile}, files...)
+	}
+	for _, file := range files {
+		contents, err := ioutil.ReadFile(file)
+		if err != nil {
+			return "", fmt.Errorf("Problem reading user data file: %s", err)
+		}
+
+		parts = append(parts, newUserDataPart(string(contents)))
+	}
+
+	if c.UserData != "" {
+		c.ctx.Data = &userDataTemplateData{
+			DropletName: c.DropletName,
+			BuildName:   c.PackerBuildName,
+		}
+		userData, err := interpolate.Render(c.UserData, &c.ctx)
+		if err != nil {
+			return "", fmt.Errorf("Problem rendering user data: %s", err)
+		}
+
+		parts = append(parts, newUserDataPart(userData))
+	}
+
+	if c.UserDataSSHKey && len(c.Comm.SSHPublicKey) > 0 {
+		parts = append(parts, sshKeyUserDataPart(c.Comm.SSHPublicKey))
+	}
+
+	userData, err := combineUserData(parts)
+	if err != nil {
+		return "", fmt.Errorf("Problem combining user data: %s", err)
+	}
+
+	return userData, nil
+}
+
 func (s *stepCreateDroplet) Cleanup(state multistep.StateBag) {
 	// If the dropletid isn't there, we probably never created it
 	if s.dropletId == 0 {
@@ -112,6 +268,11 @@ func (s *stepCreateDroplet) Cleanup(state multistep.StateBag) {
 	client := state.Get("client").(*godo.Client)
 	ui := state.Get("ui").(packersdk.Ui)
 
+	if keepDropletForResume(state) {
+		ui.Say(fmt.Sprintf("Keeping droplet %d so the build can be resumed", s.dropletId))
+		return
+	}
+
 	// Destroy the droplet we just created
 	ui.Say("Destroying droplet...")
 	_, err := client.Droplets.Delete(context.TODO(), s.dropletId)
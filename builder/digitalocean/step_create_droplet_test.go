@@ -1,10 +1,19 @@
 package digitalocean
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"testing"
 
 	"github.com/digitalocean/godo"
+	docommon "github.com/digitalocean/packer-plugin-digitalocean/common"
+	"github.com/hashicorp/packer-plugin-sdk/communicator"
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
 	"github.com/stretchr/testify/require"
 )
 
@@ -200,6 +209,135 @@ func TestBuilder_buildDropletCreateRequest(t *testing.T) {
 				VPCUUID:           "",
 			},
 		},
+		{
+			name:       "volumes set in state",
+			addToState: map[string]interface{}{"volume_ids": []string{"vol-1", "vol-2"}},
+			in: &Config{
+				DropletName: "ubuntu-20-04-x64-build",
+				Region:      "nyc3",
+				Size:        "s-1vcpu-1gb",
+				Image:       "ubuntu-20-04-x64",
+			},
+			out: &godo.DropletCreateRequest{
+				Name:    "ubuntu-20-04-x64-build",
+				Region:  "nyc3",
+				Size:    "s-1vcpu-1gb",
+				Image:   godo.DropletCreateImage{ID: 0, Slug: "ubuntu-20-04-x64"},
+				SSHKeys: []godo.DropletCreateSSHKey{},
+				Volumes: []godo.DropletCreateVolume{
+					{ID: "vol-1"},
+					{ID: "vol-2"},
+				},
+				Backups:           false,
+				IPv6:              false,
+				PrivateNetworking: false,
+				Monitoring:        false,
+				UserData:          "",
+				VPCUUID:           "",
+			},
+		},
+		{
+			name:       "firewall tag set in state",
+			addToState: map[string]interface{}{"firewall_tag": "packer-firewall"},
+			in: &Config{
+				DropletName: "ubuntu-20-04-x64-build",
+				Region:      "nyc3",
+				Size:        "s-1vcpu-1gb",
+				Image:       "ubuntu-20-04-x64",
+				Tags:        []string{"build"},
+			},
+			out: &godo.DropletCreateRequest{
+				Name:              "ubuntu-20-04-x64-build",
+				Region:            "nyc3",
+				Size:              "s-1vcpu-1gb",
+				Image:             godo.DropletCreateImage{ID: 0, Slug: "ubuntu-20-04-x64"},
+				SSHKeys:           []godo.DropletCreateSSHKey{},
+				Backups:           false,
+				IPv6:              false,
+				PrivateNetworking: false,
+				Monitoring:        false,
+				UserData:          "",
+				Tags:              []string{"build", "packer-firewall"},
+				VPCUUID:           "",
+			},
+		},
+		{
+			name: "build and resume tags set in state",
+			addToState: map[string]interface{}{
+				"resume_tag": "packer-resume-0123456789abcdef",
+				"build_tags": []string{"packer-build"},
+			},
+			in: &Config{
+				DropletName: "ubuntu-20-04-x64-build",
+				Region:      "nyc3",
+				Size:        "s-1vcpu-1gb",
+				Image:       "ubuntu-20-04-x64",
+				Tags:        []string{"build"},
+			},
+			out: &godo.DropletCreateRequest{
+				Name:              "ubuntu-20-04-x64-build",
+				Region:            "nyc3",
+				Size:              "s-1vcpu-1gb",
+				Image:             godo.DropletCreateImage{ID: 0, Slug: "ubuntu-20-04-x64"},
+				SSHKeys:           []godo.DropletCreateSSHKey{},
+				Backups:           false,
+				IPv6:              false,
+				PrivateNetworking: false,
+				Monitoring:        false,
+				UserData:          "",
+				Tags:              []string{"build", "packer-resume-0123456789abcdef", "packer-build"},
+				VPCUUID:           "",
+			},
+		},
+		{
+			name: "SSH key injected through user data",
+			in: &Config{
+				DropletName:    "ubuntu-20-04-x64-build",
+				Region:         "nyc3",
+				Size:           "s-1vcpu-1gb",
+				Image:          "ubuntu-20-04-x64",
+				UserDataSSHKey: true,
+				Comm: communicator.Config{
+					SSH: communicator.SSH{SSHPublicKey: []byte(testPublicKey)},
+				},
+			},
+			out: &godo.DropletCreateRequest{
+				Name:              "ubuntu-20-04-x64-build",
+				Region:            "nyc3",
+				Size:              "s-1vcpu-1gb",
+				Image:             godo.DropletCreateImage{ID: 0, Slug: "ubuntu-20-04-x64"},
+				SSHKeys:           []godo.DropletCreateSSHKey{},
+				Backups:           false,
+				IPv6:              false,
+				PrivateNetworking: false,
+				Monitoring:        false,
+				UserData:          sshKeyCloudConfig([]byte(testPublicKey)),
+				VPCUUID:           "",
+			},
+		},
+		{
+			name:       "image resolved from image_filter",
+			addToState: map[string]interface{}{"source_image_id": "4242"},
+			in: &Config{
+				DropletName: "ubuntu-20-04-x64-build",
+				Region:      "nyc3",
+				Size:        "s-1vcpu-1gb",
+				ImageFilter: &docommon.ImageFilterOptions{Name: "base"},
+			},
+			out: &godo.DropletCreateRequest{
+				Name:              "ubuntu-20-04-x64-build",
+				Region:            "nyc3",
+				Size:              "s-1vcpu-1gb",
+				Image:             godo.DropletCreateImage{ID: 4242, Slug: ""},
+				SSHKeys:           []godo.DropletCreateSSHKey{},
+				Backups:           false,
+				IPv6:              false,
+				PrivateNetworking: false,
+				Monitoring:        false,
+				UserData:          "",
+				VPCUUID:           "",
+			},
+		},
 	}
 
 	for _, tt := range imageTypeTests {
@@ -221,3 +359,91 @@ func TestBuilder_buildDropletCreateRequest(t *testing.T) {
 		})
 	}
 }
+
+func TestStepCreateDroplet_FallbackRegions(t *testing.T) {
+	var attempts []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Region string `json:"region"`
+			Size   string `json:"size"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		attempts = append(attempts, req.Region+"/"+req.Size)
+
+		switch {
+		case req.Region == "nyc3":
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			_, _ = w.Write([]byte(`{"id":"unprocessable_entity","message":"Size is not available in this region."}`))
+		case req.Size == "s-1vcpu-1gb":
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(`{"id":"service_unavailable","message":"Droplet capacity is temporarily unavailable."}`))
+		default:
+			w.WriteHeader(http.StatusAccepted)
+			_, _ = w.Write([]byte(`{"droplet":{"id":1234}}`))
+		}
+	}))
+	defer server.Close()
+
+	client := godo.NewClient(nil)
+	client.BaseURL, _ = url.Parse(server.URL)
+
+	state := new(multistep.BasicStateBag)
+	state.Put("client", client)
+	state.Put("ui", packersdk.TestUi(t))
+	state.Put("config", &Config{
+		DropletName:     "packer-test",
+		Region:          "nyc3",
+		Size:            "s-1vcpu-1gb",
+		Image:           "ubuntu-20-04-x64",
+		FallbackRegions: []string{"sfo3"},
+		FallbackSizes:   []string{"s-2vcpu-2gb"},
+	})
+
+	step := new(stepCreateDroplet)
+	action := step.Run(context.Background(), state)
+	require.Equal(t, multistep.ActionContinue, action, "%v", state.Get("error"))
+	require.Equal(t, []string{
+		"nyc3/s-1vcpu-1gb", "nyc3/s-2vcpu-2gb", "sfo3/s-1vcpu-1gb", "sfo3/s-2vcpu-2gb",
+	}, attempts)
+	require.Equal(t, "sfo3", state.Get("build_region"))
+	require.Equal(t, "s-2vcpu-2gb", state.Get("droplet_size"))
+	require.Equal(t, 1234, state.Get("droplet_id"))
+}
+
+func TestStepCreateDroplet_NonCapacityError(t *testing.T) {
+	messages := map[string]string{
+		"invalid image":          "You specified an invalid image.",
+		"image not in region":    "The image is not available in this region.",
+		"ssh key not in account": "SSH key is unavailable for this account.",
+	}
+
+	for name, message := range messages {
+		t.Run(name, func(t *testing.T) {
+			attempts := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				attempts++
+				w.WriteHeader(http.StatusUnprocessableEntity)
+				_, _ = fmt.Fprintf(w, `{"id":"unprocessable_entity","message":%q}`, message)
+			}))
+			defer server.Close()
+
+			client := godo.NewClient(nil)
+			client.BaseURL, _ = url.Parse(server.URL)
+
+			state := new(multistep.BasicStateBag)
+			state.Put("client", client)
+			state.Put("ui", packersdk.TestUi(t))
+			state.Put("config", &Config{
+				Region:          "nyc3",
+				Size:            "s-1vcpu-1gb",
+				Image:           "invalid",
+				FallbackRegions: []string{"sfo3"},
+			})
+
+			step := new(stepCreateDroplet)
+			action := step.Run(context.Background(), state)
+			require.Equal(t, multistep.ActionHalt, action)
+			require.Equal(t, 1, attempts)
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package digitalocean
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/uuid"
+)
+
+// publicIPURL is queried to find the public IP of the machine running Packer
+// when no source CIDRs are configured for the temporary firewall.
+var publicIPURL = "https://checkip.amazonaws.com"
+
+type stepCreateFirewall struct {
+	firewallId string
+	tag        string
+}
+
+func (s *stepCreateFirewall) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	client := state.Get("client").(*godo.Client)
+	ui := state.Get("ui").(packersdk.Ui)
+	c := state.Get("config").(*Config)
+
+	sources := c.TemporaryFirewallSourceCIDRs
+	if len(sources) == 0 {
+		ui.Say("Detecting public IP address for the temporary firewall...")
+		cidr, err := detectPublicIPCIDR(ctx)
+		if err != nil {
+			err := fmt.Errorf("Error detecting public IP address: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		sources = []string{cidr}
+	}
+
+	ui.Say(fmt.Sprintf("Creating temporary firewall allowing %s...", strings.Join(sources, ", ")))
+
+	// The firewall is applied to the droplet through this tag as soon as
+	// it is created, so the communicator port is never left open.
+	name := fmt.Sprintf("packer-%s", uuid.TimeOrderedUUID())
+	_, _, err := client.Tags.Create(ctx, &godo.TagCreateRequest{Name: name})
+	if err != nil {
+		err := fmt.Errorf("Error creating temporary firewall tag: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	// We use this to check cleanup
+	s.tag = name
+
+	allDestinations := &godo.Destinations{Addresses: []string{"0.0.0.0/0", "::/0"}}
+	firewall, _, err := client.Firewalls.Create(ctx, &godo.FirewallRequest{
+		Name: name,
+		InboundRules: []godo.InboundRule{
+			{
+				Protocol:  "tcp",
+				PortRange: strconv.Itoa(c.Comm.Port()),
+				Sources:   &godo.Sources{Addresses: sources},
+			},
+		},
+		OutboundRules: []godo.OutboundRule{
+			{Protocol: "tcp", PortRange: "all", Destinations: allDestinations},
+			{Protocol: "udp", PortRange: "all", Destinations: allDestinations},
+			{Protocol: "icmp", Destinations: allDestinations},
+		},
+		Tags: []string{name},
+	})
+	if err != nil {
+		err := fmt.Errorf("Error creating temporary firewall: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	// We use this to check cleanup
+	s.firewallId = firewall.ID
+
+	log.Printf("temporary firewall id: %s", firewall.ID)
+
+	// Remember the tag so it is added to the droplet
+	state.Put("firewall_tag", name)
+
+	return multistep.ActionContinue
+}
+
+func (s *stepCreateFirewall) Cleanup(state multistep.StateBag) {
+	// If no tag is set, then we never created anything, so just return
+	if s.tag == "" {
+		return
+	}
+
+	client := state.Get("client").(*godo.Client)
+	ui := state.Get("ui").(packersdk.Ui)
+
+	if s.firewallId != "" {
+		ui.Say("Deleting temporary firewall...")
+		_, err := client.Firewalls.Delete(context.TODO(), s.firewallId)
+		if err != nil {
+			log.Printf("Error cleaning up firewall: %s", err)
+			ui.Error(fmt.Sprintf(
+				"Error cleaning up firewall. Please delete the firewall manually: %s", err))
+		}
+	}
+
+	_, err := client.Tags.Delete(context.TODO(), s.tag)
+	if err != nil {
+		log.Printf("Error cleaning up firewall tag: %s", err)
+	}
+}
+
+// detectPublicIPCIDR returns the public IP of the machine running Packer as
+// a single address CIDR block.
+func detectPublicIPCIDR(ctx context.Context) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, publicIPURL, nil)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status from %s: %s", publicIPURL, resp.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64))
+	if err != nil {
+		return "", err
+	}
+
+	ip := net.ParseIP(strings.TrimSpace(string(body)))
+	if ip == nil {
+		return "", fmt.Errorf("invalid IP address returned by %s: %q", publicIPURL, body)
+	}
+
+	if ip.To4() != nil {
+		return ip.String() + "/32", nil
+	}
+	return ip.String() + "/128", nil
+}
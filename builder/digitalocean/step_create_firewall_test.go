@@ -0,0 +1,128 @@
+package digitalocean
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectPublicIPCIDR(t *testing.T) {
+	tests := []struct {
+		name          string
+		body          string
+		expected      string
+		expectedError string
+	}{
+		{name: "IPv4", body: "203.0.113.7\n", expected: "203.0.113.7/32"},
+		{name: "IPv6", body: "2001:db8::1\n", expected: "2001:db8::1/128"},
+		{name: "invalid", body: "<html>", expectedError: "invalid IP address"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, tt.body)
+			}))
+			defer server.Close()
+
+			defer func(url string) { publicIPURL = url }(publicIPURL)
+			publicIPURL = server.URL
+
+			cidr, err := detectPublicIPCIDR(context.Background())
+			if tt.expectedError != "" {
+				require.ErrorContains(t, err, tt.expectedError)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, cidr)
+		})
+	}
+}
+
+func TestStepCreateFirewall(t *testing.T) {
+	var (
+		tags        []string
+		firewall    godo.FirewallRequest
+		deletedFw   []string
+		deletedTags []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/v2/tags":
+			var req godo.TagCreateRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Errorf("decoding tag request: %s", err)
+			}
+			tags = append(tags, req.Name)
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprintf(w, `{"tag":{"name":%q}}`, req.Name)
+		case r.Method == http.MethodPost && r.URL.Path == "/v2/firewalls":
+			if err := json.NewDecoder(r.Body).Decode(&firewall); err != nil {
+				t.Errorf("decoding firewall request: %s", err)
+			}
+			w.WriteHeader(http.StatusAccepted)
+			fmt.Fprint(w, `{"firewall":{"id":"fw-1"}}`)
+		case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/v2/firewalls/"):
+			deletedFw = append(deletedFw, strings.TrimPrefix(r.URL.Path, "/v2/firewalls/"))
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/v2/tags/"):
+			deletedTags = append(deletedTags, strings.TrimPrefix(r.URL.Path, "/v2/tags/"))
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	client := godo.NewClient(nil)
+	client.BaseURL, _ = url.Parse(server.URL)
+
+	c := &Config{
+		Region:                       "nyc3",
+		Size:                         "s-1vcpu-1gb",
+		Image:                        "ubuntu-22-04-x64",
+		TemporaryFirewall:            true,
+		TemporaryFirewallSourceCIDRs: []string{"203.0.113.0/24", "2001:db8::/32"},
+	}
+	c.Comm.Type = "ssh"
+	c.Comm.SSHPort = 2222
+
+	state := new(multistep.BasicStateBag)
+	state.Put("client", client)
+	state.Put("ui", packersdk.TestUi(t))
+	state.Put("config", c)
+
+	step := new(stepCreateFirewall)
+	action := step.Run(context.Background(), state)
+	require.Equal(t, multistep.ActionContinue, action, "%v", state.Get("error"))
+
+	require.Len(t, tags, 1)
+	tag := tags[0]
+	require.Regexp(t, "^packer-", tag)
+	require.Equal(t, []string{tag}, firewall.Tags)
+	require.Len(t, firewall.InboundRules, 1)
+	require.Equal(t, "tcp", firewall.InboundRules[0].Protocol)
+	require.Equal(t, "2222", firewall.InboundRules[0].PortRange)
+	require.Equal(t, []string{"203.0.113.0/24", "2001:db8::/32"}, firewall.InboundRules[0].Sources.Addresses)
+
+	// The droplet is created with the firewall tag
+	req, err := new(stepCreateDroplet).buildDropletCreateRequest(state)
+	require.NoError(t, err)
+	require.Contains(t, req.Tags, tag)
+
+	step.Cleanup(state)
+	require.Equal(t, []string{"fw-1"}, deletedFw)
+	require.Equal(t, []string{tag}, deletedTags)
+}
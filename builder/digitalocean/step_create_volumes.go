@@ -0,0 +1,163 @@
+package digitalocean
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/digitalocean/godo"
+	docommon "github.com/digitalocean/packer-plugin-digitalocean/common"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/packerbuilderdata"
+)
+
+type stepCreateVolumes struct {
+	volumeIds []string
+}
+
+func (s *stepCreateVolumes) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	client := state.Get("client").(*godo.Client)
+	ui := state.Get("ui").(packersdk.Ui)
+	c := state.Get("config").(*Config)
+	generatedData := &packerbuilderdata.GeneratedData{State: state}
+
+	if len(c.Volumes) == 0 {
+		generatedData.Put("VolumeMountPaths", "")
+		return multistep.ActionContinue
+	}
+
+	volumeIds := make([]string, 0, len(c.Volumes))
+	mountPaths := make([]string, 0, len(c.Volumes))
+	for _, v := range c.Volumes {
+		if v.VolumeID != "" {
+			volume, _, err := client.Storage.GetVolume(ctx, v.VolumeID)
+			if err != nil {
+				err := fmt.Errorf("Error retrieving volume %s: %s", v.VolumeID, err)
+				state.Put("error", err)
+				ui.Error(err.Error())
+				return multistep.ActionHalt
+			}
+
+			if volume.Region == nil || volume.Region.Slug != c.Region {
+				err := fmt.Errorf("Volume %s is not in region %s", v.VolumeID, c.Region)
+				state.Put("error", err)
+				ui.Error(err.Error())
+				return multistep.ActionHalt
+			}
+
+			if len(volume.DropletIDs) > 0 {
+				err := fmt.Errorf("Volume %s is already attached to droplet %d", v.VolumeID, volume.DropletIDs[0])
+				state.Put("error", err)
+				ui.Error(err.Error())
+				return multistep.ActionHalt
+			}
+
+			ui.Say(fmt.Sprintf("Using existing volume %s (%s)...", volume.Name, volume.ID))
+			volumeIds = append(volumeIds, volume.ID)
+			if volume.FilesystemType != "" {
+				mountPaths = append(mountPaths, volumeMountPath(volume.Name))
+			}
+			continue
+		}
+
+		tags := c.Tags
+		if buildTags, ok := state.GetOk("build_tags"); ok {
+			tags = append(append([]string{}, tags...), buildTags.([]string)...)
+		}
+
+		ui.Say(fmt.Sprintf("Creating volume %s (%d GiB)...", v.Name, v.Size))
+		volume, _, err := client.Storage.CreateVolume(ctx, &godo.VolumeCreateRequest{
+			Region:         c.Region,
+			Name:           v.Name,
+			SizeGigaBytes:  v.Size,
+			FilesystemType: v.FilesystemType,
+			Tags:           tags,
+		})
+		if err != nil {
+			err := fmt.Errorf("Error creating volume: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+
+		// We use this in cleanup
+		s.volumeIds = append(s.volumeIds, volume.ID)
+
+		log.Printf("temporary volume id: %s", volume.ID)
+		volumeIds = append(volumeIds, volume.ID)
+		if volume.FilesystemType != "" {
+			mountPaths = append(mountPaths, volumeMountPath(volume.Name))
+		}
+	}
+
+	// Store the volume ids so they are attached to the droplet
+	state.Put("volume_ids", volumeIds)
+	generatedData.Put("VolumeMountPaths", strings.Join(mountPaths, ","))
+
+	return multistep.ActionContinue
+}
+
+func (s *stepCreateVolumes) Cleanup(state multistep.StateBag) {
+	// If no volumes were created, there is nothing to delete
+	if len(s.volumeIds) == 0 {
+		return
+	}
+
+	client := state.Get("client").(*godo.Client)
+	ui := state.Get("ui").(packersdk.Ui)
+	c := state.Get("config").(*Config)
+
+	ui.Say("Deleting temporary volumes...")
+	for _, id := range s.volumeIds {
+		if err := deleteVolume(client, id, c.PollInterval, c.StateTimeout); err != nil {
+			ui.Error(fmt.Sprintf(
+				"Error deleting volume %s. Please delete it manually: %s", id, err))
+		}
+	}
+}
+
+// deleteVolume detaches the volume from any droplet it is still attached to
+// and deletes it once it is free.
+func deleteVolume(client *godo.Client, volumeId string, pollInterval, timeout time.Duration) error {
+	volume, _, err := client.Storage.GetVolume(context.TODO(), volumeId)
+	if err != nil {
+		return err
+	}
+
+	for _, dropletId := range volume.DropletIDs {
+		log.Printf("Detaching volume %s from droplet %d", volumeId, dropletId)
+		if _, _, err := client.StorageActions.DetachByDropletID(context.TODO(), volumeId, dropletId); err != nil {
+			// The droplet may already be on its way out, which detaches the
+			// volume too, so only log this.
+			log.Printf("Error detaching volume %s: %s", volumeId, err)
+		}
+	}
+
+	err = docommon.WaitFor(context.TODO(), docommon.WaitOptions{
+		Description:  fmt.Sprintf("volume %s to detach", volumeId),
+		PollInterval: pollInterval,
+		Timeout:      timeout,
+	}, func(ctx context.Context) (bool, error) {
+		volume, _, err := client.Storage.GetVolume(ctx, volumeId)
+		if err != nil {
+			return false, err
+		}
+
+		return len(volume.DropletIDs) == 0, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = client.Storage.DeleteVolume(context.TODO(), volumeId)
+	return err
+}
+
+// volumeMountPath returns the path DigitalOcean automatically mounts a
+// formatted volume at.
+func volumeMountPath(name string) string {
+	return "/mnt/" + strings.ReplaceAll(name, "-", "_")
+}
@@ -0,0 +1,51 @@
+package digitalocean
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// stepDetachVolumes detaches the build volumes from the powered off droplet
+// so that the snapshot is taken without any block storage attached.
+type stepDetachVolumes struct{}
+
+func (s *stepDetachVolumes) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	volumeIds, ok := state.GetOk("volume_ids")
+	if !ok || len(volumeIds.([]string)) == 0 {
+		return multistep.ActionContinue
+	}
+
+	client := state.Get("client").(*godo.Client)
+	ui := state.Get("ui").(packersdk.Ui)
+	c := state.Get("config").(*Config)
+	dropletId := state.Get("droplet_id").(int)
+
+	ui.Say("Detaching volumes...")
+	for _, id := range volumeIds.([]string) {
+		action, _, err := client.StorageActions.DetachByDropletID(ctx, id, dropletId)
+		if err != nil {
+			err := fmt.Errorf("Error detaching volume %s: %s", id, err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+
+		if err := waitForVolumeActionState(ctx, godo.ActionCompleted, id, action.ID,
+			client, c.PollInterval, c.StateTimeout); err != nil {
+			err := fmt.Errorf("Error waiting for volume %s to detach: %s", id, err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepDetachVolumes) Cleanup(state multistep.StateBag) {
+	// no cleanup
+}
@@ -0,0 +1,161 @@
+package digitalocean
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// stepDryRun looks up the resources the build depends on and prints what it
+// would create, in place of the steps creating anything.
+type stepDryRun struct {
+	// tempSSHKey is set when a temporary SSH key would be imported and
+	// added to the droplet create request.
+	tempSSHKey bool
+}
+
+func (s *stepDryRun) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	client := state.Get("client").(*godo.Client)
+	ui := state.Get("ui").(packersdk.Ui)
+	c := state.Get("config").(*Config)
+
+	var buildRegion string
+	if c.SourceDropletID != 0 {
+		droplet, _, err := client.Droplets.Get(ctx, c.SourceDropletID)
+		if err != nil {
+			err := fmt.Errorf("Error retrieving source droplet: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		if droplet.Region != nil {
+			buildRegion = droplet.Region.Slug
+		}
+
+		ui.Say(fmt.Sprintf("Would snapshot existing droplet %d (%s)", droplet.ID, droplet.Name))
+	} else {
+		if c.VPCUUID != "" {
+			if err := checkVPC(ctx, client, c.VPCUUID, c.Region); err != nil {
+				state.Put("error", err)
+				ui.Error(err.Error())
+				return multistep.ActionHalt
+			}
+		}
+
+		req, err := new(stepCreateDroplet).buildDropletCreateRequest(state)
+		if err != nil {
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		buildRegion = req.Region
+
+		// The user data may hold secrets, such as the generated WinRM
+		// password
+		printed := *req
+		if printed.UserData != "" {
+			printed.UserData = fmt.Sprintf("<sensitive, %d bytes>", len(req.UserData))
+		}
+
+		ui.Say("Would create droplet with request:")
+		ui.Message(godo.Stringify(printed))
+		if s.tempSSHKey {
+			ui.Message("A temporary SSH key would be imported and added to ssh_keys")
+		}
+		if len(c.FallbackRegions) > 0 || len(c.FallbackSizes) > 0 {
+			ui.Message(fmt.Sprintf("Fallback regions: %s, fallback sizes: %s",
+				formatList(c.FallbackRegions), formatList(c.FallbackSizes)))
+		}
+		ui.Message(fmt.Sprintf("Droplet tags: %s", formatList(req.Tags)))
+	}
+
+	snapshots, err := findSnapshotsByName(ctx, client, c.SnapshotName)
+	if err != nil {
+		err := fmt.Errorf("Error looking up snapshots named %s: %s", c.SnapshotName, err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	ui.Say(fmt.Sprintf("Would create snapshot %s", c.SnapshotName))
+	ui.Message(fmt.Sprintf("Snapshot regions: %s", formatList(snapshotRegions(buildRegion, c.SnapshotRegions))))
+	ui.Message(fmt.Sprintf("Snapshot tags: %s", formatList(c.SnapshotTags)))
+	for _, snapshot := range snapshots {
+		ui.Error(fmt.Sprintf("Warning: snapshot name %s is already used by snapshot %s, created at %s",
+			c.SnapshotName, snapshot.ID, snapshot.Created))
+	}
+
+	ui.Say("Dry run complete, no resources were created")
+	return multistep.ActionContinue
+}
+
+func (s *stepDryRun) Cleanup(state multistep.StateBag) {
+	// no cleanup
+}
+
+// checkVPC returns an error if the VPC does not exist or is not in the
+// region.
+func checkVPC(ctx context.Context, client *godo.Client, id string, region string) error {
+	vpc, _, err := client.VPCs.Get(ctx, id)
+	if err != nil {
+		return fmt.Errorf("Error retrieving VPC %s: %s", id, err)
+	}
+
+	if vpc.RegionSlug != region {
+		return fmt.Errorf("VPC %s is in region %s, not in region %s", id, vpc.RegionSlug, region)
+	}
+
+	return nil
+}
+
+// findSnapshotsByName returns the droplet snapshots with the name.
+func findSnapshotsByName(ctx context.Context, client *godo.Client, name string) ([]godo.Snapshot, error) {
+	var found []godo.Snapshot
+
+	opt := &godo.ListOptions{Page: 1, PerPage: 200}
+	for {
+		snapshots, resp, err := client.Snapshots.ListDroplet(ctx, opt)
+		if err != nil {
+			return nil, err
+		}
+		for _, snapshot := range snapshots {
+			if snapshot.Name == name {
+				found = append(found, snapshot)
+			}
+		}
+
+		if resp.Links == nil || resp.Links.IsLastPage() {
+			return found, nil
+		}
+		page, err := resp.Links.CurrentPage()
+		if err != nil {
+			return nil, err
+		}
+		opt.Page = page + 1
+	}
+}
+
+// snapshotRegions returns the regions the snapshot is made available in:
+// the build region, then the other snapshot regions.
+func snapshotRegions(buildRegion string, regions []string) []string {
+	planned := []string{buildRegion}
+	for _, region := range regions {
+		if !contains(planned, region) {
+			planned = append(planned, region)
+		}
+	}
+
+	return planned
+}
+
+func formatList(list []string) string {
+	if len(list) == 0 {
+		return "(none)"
+	}
+
+	return strings.Join(list, ", ")
+}
@@ -0,0 +1,131 @@
+package digitalocean
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/stretchr/testify/require"
+)
+
+func testDryRunServer(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v2/vpcs/", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"vpc":{"id":"5a4981aa","region":"nyc3"}}`))
+	})
+	mux.HandleFunc("/v2/snapshots", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "droplet", r.URL.Query().Get("resource_type"))
+		_, _ = w.Write([]byte(`{"snapshots":[
+			{"id":"6372321","name":"web-1","created_at":"2024-01-01T00:00:00Z"},
+			{"id":"6372322","name":"web-2","created_at":"2024-01-02T00:00:00Z"}
+		]}`))
+	})
+	mux.HandleFunc("/v2/droplets/", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodGet, r.Method)
+		_, _ = w.Write([]byte(`{"droplet":{"id":1234,"name":"existing","region":{"slug":"ams3"}}}`))
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request: %s %s", r.Method, r.URL)
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	return httptest.NewServer(mux)
+}
+
+func TestStepDryRun(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      *Config
+		action      multistep.StepAction
+		contains    []string
+		notContains []string
+	}{
+		{
+			name: "new droplet",
+			config: &Config{
+				DropletName:       "packer-test",
+				Region:            "nyc3",
+				Size:              "s-1vcpu-1gb",
+				Image:             "ubuntu-20-04-x64",
+				PrivateNetworking: true,
+				VPCUUID:           "5a4981aa",
+				SnapshotName:      "web-2",
+				SnapshotRegions:   []string{"nyc3", "sfo3"},
+				SnapshotTags:      []string{"web"},
+				UserData:          "#!/bin/sh\necho secret-password",
+			},
+			action: multistep.ActionContinue,
+			contains: []string{
+				`Name:"packer-test"`,
+				`VPCUUID:"5a4981aa"`,
+				"Snapshot regions: nyc3, sfo3",
+				"Snapshot tags: web",
+				"Droplet tags: packer-build",
+				"snapshot name web-2 is already used by snapshot 6372322",
+				`UserData:"<sensitive, 30 bytes>"`,
+			},
+			notContains: []string{"secret-password"},
+		},
+		{
+			name: "VPC in another region",
+			config: &Config{
+				Region:            "sfo3",
+				Size:              "s-1vcpu-1gb",
+				Image:             "ubuntu-20-04-x64",
+				PrivateNetworking: true,
+				VPCUUID:           "5a4981aa",
+			},
+			action:   multistep.ActionHalt,
+			contains: []string{"VPC 5a4981aa is in region nyc3, not in region sfo3"},
+		},
+		{
+			name: "source droplet",
+			config: &Config{
+				SourceDropletID: 1234,
+				SnapshotName:    "web-3",
+				SnapshotRegions: []string{"fra1"},
+			},
+			action: multistep.ActionContinue,
+			contains: []string{
+				"Would snapshot existing droplet 1234 (existing)",
+				"Snapshot regions: ams3, fra1",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := testDryRunServer(t)
+			defer server.Close()
+
+			client := godo.NewClient(nil)
+			client.BaseURL, _ = url.Parse(server.URL)
+
+			var out bytes.Buffer
+			state := new(multistep.BasicStateBag)
+			state.Put("client", client)
+			state.Put("ui", &packersdk.BasicUi{
+				Reader:      new(bytes.Buffer),
+				Writer:      &out,
+				ErrorWriter: &out,
+			})
+			state.Put("config", tt.config)
+			state.Put("build_tags", []string{"packer-build"})
+
+			action := new(stepDryRun).Run(context.Background(), state)
+			require.Equal(t, tt.action, action, "%v", state.Get("error"))
+			for _, s := range tt.contains {
+				require.Contains(t, out.String(), s)
+			}
+			for _, s := range tt.notContains {
+				require.NotContains(t, out.String(), s)
+			}
+		})
+	}
+}
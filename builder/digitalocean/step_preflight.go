@@ -0,0 +1,220 @@
+package digitalocean
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// stepPreflight checks that the regions, sizes, and image of the build are
+// compatible before any resource is created. Every problem found is
+// reported at once.
+type stepPreflight struct{}
+
+func (s *stepPreflight) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	client := state.Get("client").(*godo.Client)
+	ui := state.Get("ui").(packersdk.Ui)
+	c := state.Get("config").(*Config)
+
+	ui.Say("Checking region, size, and image compatibility...")
+
+	regions, err := listRegions(ctx, client)
+	if err != nil {
+		err := fmt.Errorf("DigitalOcean: Unable to get regions, %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	var sizes []godo.Size
+	var image *godo.Image
+	if c.SourceDropletID == 0 {
+		sizes, err = listSizes(ctx, client)
+		if err != nil {
+			err := fmt.Errorf("DigitalOcean: Unable to get sizes, %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+
+		// The image may have been resolved from image_filter
+		imageName := c.Image
+		if sourceImage, ok := state.GetOk("source_image_id"); ok {
+			imageName = sourceImage.(string)
+		}
+
+		image, err = getImage(ctx, client, imageName)
+		if err != nil {
+			err := fmt.Errorf("DigitalOcean: Unable to get image %s, %s", imageName, err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+	}
+
+	if errs := preflightChecks(c, regions, sizes, image); len(errs) > 0 {
+		err := fmt.Errorf("Pre-flight checks failed: %s", &packersdk.MultiError{Errors: errs})
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepPreflight) Cleanup(state multistep.StateBag) {
+	// no cleanup
+}
+
+func listRegions(ctx context.Context, client *godo.Client) ([]godo.Region, error) {
+	opt := &godo.ListOptions{Page: 1, PerPage: 200}
+	var list []godo.Region
+	for {
+		regions, resp, err := client.Regions.List(ctx, opt)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, regions...)
+
+		if resp.Links == nil || resp.Links.IsLastPage() {
+			return list, nil
+		}
+		page, err := resp.Links.CurrentPage()
+		if err != nil {
+			return nil, err
+		}
+		opt.Page = page + 1
+	}
+}
+
+func listSizes(ctx context.Context, client *godo.Client) ([]godo.Size, error) {
+	opt := &godo.ListOptions{Page: 1, PerPage: 200}
+	var list []godo.Size
+	for {
+		sizes, resp, err := client.Sizes.List(ctx, opt)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, sizes...)
+
+		if resp.Links == nil || resp.Links.IsLastPage() {
+			return list, nil
+		}
+		page, err := resp.Links.CurrentPage()
+		if err != nil {
+			return nil, err
+		}
+		opt.Page = page + 1
+	}
+}
+
+// getImage looks up an image by ID or by slug.
+func getImage(ctx context.Context, client *godo.Client, image string) (*godo.Image, error) {
+	createImage := getImageType(image)
+	if createImage.ID != 0 {
+		i, _, err := client.Images.GetByID(ctx, createImage.ID)
+		return i, err
+	}
+
+	i, _, err := client.Images.GetBySlug(ctx, createImage.Slug)
+	return i, err
+}
+
+// preflightChecks returns every incompatibility between the configured
+// regions, sizes, and image. When building from an existing droplet, only
+// the snapshot regions are checked. With fallback regions or sizes, the
+// build only needs one usable region and size combination.
+func preflightChecks(c *Config, regions []godo.Region, sizes []godo.Size, image *godo.Image) []error {
+	var errs []error
+
+	validRegions := make(map[string]godo.Region)
+	for _, r := range regions {
+		validRegions[r.Slug] = r
+	}
+
+	checkRegions := append([]string{}, c.SnapshotRegions...)
+	if c.SourceDropletID == 0 {
+		checkRegions = append(checkRegions, c.Region)
+		checkRegions = append(checkRegions, c.FallbackRegions...)
+	}
+	for _, region := range checkRegions {
+		if _, ok := validRegions[region]; !ok {
+			errs = append(errs, fmt.Errorf("invalid region: %s", region))
+		}
+	}
+
+	if c.SourceDropletID != 0 {
+		return errs
+	}
+
+	validSizes := make(map[string]godo.Size)
+	for _, s := range sizes {
+		validSizes[s.Slug] = s
+	}
+
+	candidateSizes := append([]string{c.Size}, c.FallbackSizes...)
+	for _, size := range candidateSizes {
+		if _, ok := validSizes[size]; !ok {
+			errs = append(errs, fmt.Errorf("invalid size: %s", size))
+		}
+	}
+
+	if image.Status != "" && image.Status != "available" {
+		errs = append(errs, fmt.Errorf("image %d is not available, its status is %s", image.ID, image.Status))
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	var candidateErrs []error
+	for _, region := range append([]string{c.Region}, c.FallbackRegions...) {
+		for _, size := range candidateSizes {
+			es := checkCandidate(validRegions[region], validSizes[size], image)
+			if len(es) == 0 {
+				return nil
+			}
+			candidateErrs = append(candidateErrs, es...)
+		}
+	}
+
+	return candidateErrs
+}
+
+// checkCandidate returns the reasons the droplet can not be created from the
+// image with the size in the region.
+func checkCandidate(region godo.Region, size godo.Size, image *godo.Image) []error {
+	var errs []error
+
+	if !region.Available {
+		errs = append(errs, fmt.Errorf("region %s is not available", region.Slug))
+	}
+
+	if !size.Available || !contains(region.Sizes, size.Slug) {
+		errs = append(errs, fmt.Errorf("size %s is not available in region %s", size.Slug, region.Slug))
+	}
+
+	if !contains(image.Regions, region.Slug) {
+		errs = append(errs, fmt.Errorf("image %d is not available in region %s", image.ID, region.Slug))
+	}
+
+	if image.MinDiskSize > size.Disk {
+		errs = append(errs, fmt.Errorf(
+			"image %d requires a disk of at least %d GB, but size %s has %d GB",
+			image.ID, image.MinDiskSize, size.Slug, size.Disk))
+	}
+
+	return errs
+}
+
+func contains(list []string, term string) bool {
+	for _, t := range list {
+		if t == term {
+			return true
+		}
+	}
+	return false
+}
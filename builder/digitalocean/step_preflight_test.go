@@ -0,0 +1,131 @@
+package digitalocean
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreflightChecks(t *testing.T) {
+	regions := []godo.Region{
+		{Slug: "nyc3", Available: true, Sizes: []string{"s-1vcpu-1gb", "s-2vcpu-2gb"}},
+		{Slug: "sfo3", Available: true, Sizes: []string{"s-2vcpu-2gb"}},
+		{Slug: "ams2", Available: false},
+	}
+	sizes := []godo.Size{
+		{Slug: "s-1vcpu-1gb", Available: true, Disk: 25},
+		{Slug: "s-2vcpu-2gb", Available: true, Disk: 50},
+	}
+	image := &godo.Image{ID: 1, Status: "available", MinDiskSize: 25, Regions: []string{"nyc3", "sfo3"}}
+
+	tests := []struct {
+		name           string
+		config         *Config
+		image          *godo.Image
+		expectedErrors []string
+	}{
+		{
+			name:   "compatible",
+			config: &Config{Region: "nyc3", Size: "s-1vcpu-1gb", SnapshotRegions: []string{"sfo3"}},
+			image:  image,
+		},
+		{
+			name:   "invalid slugs",
+			config: &Config{Region: "nyc4", Size: "s-1vcpu-3gb", SnapshotRegions: []string{"sfo9"}},
+			image:  image,
+			expectedErrors: []string{
+				"invalid region: sfo9",
+				"invalid region: nyc4",
+				"invalid size: s-1vcpu-3gb",
+			},
+		},
+		{
+			name:   "incompatible",
+			config: &Config{Region: "sfo3", Size: "s-1vcpu-1gb"},
+			image:  &godo.Image{ID: 1, MinDiskSize: 30, Regions: []string{"nyc3"}},
+			expectedErrors: []string{
+				"size s-1vcpu-1gb is not available in region sfo3",
+				"image 1 is not available in region sfo3",
+				"image 1 requires a disk of at least 30 GB, but size s-1vcpu-1gb has 25 GB",
+			},
+		},
+		{
+			name:   "image not available",
+			config: &Config{Region: "nyc3", Size: "s-1vcpu-1gb"},
+			image:  &godo.Image{ID: 1, Status: "pending", Regions: []string{"nyc3"}},
+			expectedErrors: []string{
+				"image 1 is not available, its status is pending",
+			},
+		},
+		{
+			name:   "fallback compatible",
+			config: &Config{Region: "ams2", Size: "s-1vcpu-1gb", FallbackRegions: []string{"sfo3"}, FallbackSizes: []string{"s-2vcpu-2gb"}},
+			image:  image,
+		},
+		{
+			name:   "source droplet",
+			config: &Config{SourceDropletID: 1, SnapshotRegions: []string{"sfo9"}},
+			expectedErrors: []string{
+				"invalid region: sfo9",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var messages []string
+			for _, err := range preflightChecks(tt.config, regions, sizes, tt.image) {
+				messages = append(messages, err.Error())
+			}
+			require.Equal(t, tt.expectedErrors, messages)
+		})
+	}
+}
+
+func TestStepPreflight_Pagination(t *testing.T) {
+	var server *httptest.Server
+	page := func(w http.ResponseWriter, r *http.Request, key, first, second string) {
+		if r.URL.Query().Get("page") == "2" {
+			fmt.Fprintf(w, `{%q:[%s],"links":{"pages":{"prev":"%s%s?page=1"}}}`, key, second, server.URL, r.URL.Path)
+			return
+		}
+		fmt.Fprintf(w, `{%q:[%s],"links":{"pages":{"next":"%s%s?page=2","last":"%s%s?page=2"}}}`,
+			key, first, server.URL, r.URL.Path, server.URL, r.URL.Path)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v2/regions", func(w http.ResponseWriter, r *http.Request) {
+		page(w, r, "regions",
+			`{"slug":"nyc3","available":true,"sizes":["s-1vcpu-1gb"]}`,
+			`{"slug":"sfo3","available":true,"sizes":["s-1vcpu-1gb","g-2vcpu-8gb"]}`)
+	})
+	mux.HandleFunc("/v2/sizes", func(w http.ResponseWriter, r *http.Request) {
+		page(w, r, "sizes",
+			`{"slug":"s-1vcpu-1gb","available":true,"disk":25}`,
+			`{"slug":"g-2vcpu-8gb","available":true,"disk":25}`)
+	})
+	mux.HandleFunc("/v2/images/ubuntu-22-04-x64", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"image":{"id":1,"status":"available","regions":["nyc3","sfo3"]}}`))
+	})
+	server = httptest.NewServer(mux)
+	defer server.Close()
+
+	client := godo.NewClient(nil)
+	client.BaseURL, _ = url.Parse(server.URL)
+
+	state := new(multistep.BasicStateBag)
+	state.Put("client", client)
+	state.Put("ui", packersdk.TestUi(t))
+	state.Put("config", &Config{Region: "sfo3", Size: "g-2vcpu-8gb", Image: "ubuntu-22-04-x64"})
+
+	action := new(stepPreflight).Run(context.Background(), state)
+	require.Equal(t, multistep.ActionContinue, action, "%v", state.Get("error"))
+}
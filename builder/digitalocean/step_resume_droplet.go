@@ -0,0 +1,191 @@
+package digitalocean
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/digitalocean/godo"
+	docommon "github.com/digitalocean/packer-plugin-digitalocean/common"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// stepResumeDroplet looks up a droplet left over from an interrupted run of
+// the same build. A healthy droplet is reused in place of stepCreateDroplet,
+// other droplets with the fingerprint tag are destroyed.
+type stepResumeDroplet struct {
+	dropletId int
+}
+
+func (s *stepResumeDroplet) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	client := state.Get("client").(*godo.Client)
+	ui := state.Get("ui").(packersdk.Ui)
+	c := state.Get("config").(*Config)
+
+	tag, err := resumeTag(c)
+	if err != nil {
+		err := fmt.Errorf("Error computing build fingerprint: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	// stepCreateDroplet tags new droplets so the next run can find them
+	state.Put("resume_tag", tag)
+
+	ui.Say(fmt.Sprintf("Looking for a droplet to resume with tag %s...", tag))
+	droplets, _, err := client.Droplets.ListByTag(ctx, tag, &godo.ListOptions{PerPage: 200})
+	if err != nil {
+		err := fmt.Errorf("Error listing droplets to resume: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	var droplet *godo.Droplet
+	for i := range droplets {
+		d := &droplets[i]
+		if droplet == nil && d.Status == "active" && !d.Locked {
+			droplet = d
+			continue
+		}
+
+		ui.Say(fmt.Sprintf("Destroying leftover droplet %d (status: %s)...", d.ID, d.Status))
+		if _, err := client.Droplets.Delete(ctx, d.ID); err != nil {
+			ui.Error(fmt.Sprintf(
+				"Error destroying droplet %d. Please destroy it manually: %s", d.ID, err))
+		}
+	}
+
+	if droplet == nil {
+		ui.Say("No droplet to resume, a new one will be created")
+		return multistep.ActionContinue
+	}
+
+	ui.Say(fmt.Sprintf("Resuming droplet %d (%s)...", droplet.ID, droplet.Name))
+
+	// The droplet must join the temporary firewall of this run
+	if firewallTag, ok := state.GetOk("firewall_tag"); ok {
+		_, err := client.Tags.TagResources(ctx, firewallTag.(string), &godo.TagResourcesRequest{
+			Resources: []godo.Resource{
+				{ID: strconv.Itoa(droplet.ID), Type: godo.DropletResourceType},
+			},
+		})
+		if err != nil {
+			err := fmt.Errorf("Error applying firewall tag to droplet: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+	}
+
+	// We use this in cleanup
+	s.dropletId = droplet.ID
+
+	// Store the source image ID and
+	// other miscellaneous info for HCP Packer
+	if droplet.Image != nil {
+		state.Put("source_image_id", strconv.Itoa(droplet.Image.ID))
+	}
+	state.Put("droplet_size", droplet.SizeSlug)
+	state.Put("droplet_name", droplet.Name)
+	if droplet.Region != nil {
+		state.Put("build_region", droplet.Region.Slug)
+	}
+
+	// Store the droplet id for later
+	state.Put("droplet_id", droplet.ID)
+	// instance_id is the generic term used so that users can have access to the
+	// instance id inside of the provisioners, used in step_provision.
+	state.Put("instance_id", droplet.ID)
+
+	return multistep.ActionContinue
+}
+
+func (s *stepResumeDroplet) Cleanup(state multistep.StateBag) {
+	// If the dropletid isn't there, no droplet was resumed
+	if s.dropletId == 0 {
+		return
+	}
+
+	ui := state.Get("ui").(packersdk.Ui)
+	if keepDropletForResume(state) {
+		ui.Say(fmt.Sprintf("Keeping droplet %d so the build can be resumed", s.dropletId))
+		return
+	}
+
+	client := state.Get("client").(*godo.Client)
+
+	ui.Say("Destroying droplet...")
+	_, err := client.Droplets.Delete(context.TODO(), s.dropletId)
+	if err != nil {
+		ui.Error(fmt.Sprintf(
+			"Error destroying droplet. Please destroy it manually: %s", err))
+	}
+}
+
+// keepDropletForResume reports whether the build droplet should be kept
+// for the next run, which is when resume is enabled and the build did not
+// complete.
+func keepDropletForResume(state multistep.StateBag) bool {
+	c := state.Get("config").(*Config)
+	if !c.Resume {
+		return false
+	}
+
+	_, cancelled := state.GetOk(multistep.StateCancelled)
+	_, halted := state.GetOk(multistep.StateHalted)
+	return cancelled || halted
+}
+
+// resumeTag returns the tag identifying the droplets of this build. It is a
+// hash of the build name and of the settings the droplet is created with,
+// so that a changed template does not resume a stale droplet.
+func resumeTag(c *Config) (string, error) {
+	fingerprint := struct {
+		BuildName         string
+		Region            string
+		FallbackRegions   []string
+		Size              string
+		FallbackSizes     []string
+		Image             string
+		ImageFilter       *docommon.ImageFilterOptions
+		PrivateNetworking bool
+		IPv6              bool
+		VPCUUID           string
+		UserData          string
+		UserDataFile      string
+		UserDataFiles     []string
+		WinRMUserData     bool
+		Tags              []string
+		SSHKeyID          int
+	}{
+		BuildName:         c.PackerBuildName,
+		Region:            c.Region,
+		FallbackRegions:   c.FallbackRegions,
+		Size:              c.Size,
+		FallbackSizes:     c.FallbackSizes,
+		Image:             c.Image,
+		ImageFilter:       c.ImageFilter,
+		PrivateNetworking: c.PrivateNetworking,
+		IPv6:              c.IPv6,
+		VPCUUID:           c.VPCUUID,
+		UserData:          c.UserData,
+		UserDataFile:      c.UserDataFile,
+		UserDataFiles:     c.UserDataFiles,
+		WinRMUserData:     c.WinRMUserDataPassword,
+		Tags:              c.Tags,
+		SSHKeyID:          c.SSHKeyID,
+	}
+
+	b, err := json.Marshal(fingerprint)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(b)
+	return "packer-resume-" + hex.EncodeToString(sum[:8]), nil
+}
@@ -0,0 +1,90 @@
+package digitalocean
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/packer-plugin-sdk/common"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResumeTag(t *testing.T) {
+	c := &Config{
+		PackerConfig: common.PackerConfig{PackerBuildName: "web"},
+		Region:       "nyc3",
+		Size:         "s-1vcpu-1gb",
+		Image:        "ubuntu-20-04-x64",
+	}
+
+	tag, err := resumeTag(c)
+	require.NoError(t, err)
+	require.Regexp(t, "^packer-resume-[0-9a-f]{16}$", tag)
+
+	same, err := resumeTag(&Config{
+		PackerConfig: common.PackerConfig{PackerBuildName: "web"},
+		Region:       "nyc3",
+		Size:         "s-1vcpu-1gb",
+		Image:        "ubuntu-20-04-x64",
+		DropletName:  "packer-other",
+	})
+	require.NoError(t, err)
+	require.Equal(t, tag, same)
+
+	c.PackerBuildName = "db"
+	other, err := resumeTag(c)
+	require.NoError(t, err)
+	require.NotEqual(t, tag, other)
+}
+
+func TestStepResumeDroplet(t *testing.T) {
+	var deleted []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/v2/droplets":
+			_, _ = w.Write([]byte(`{"droplets":[
+				{"id":1,"name":"packer-1","status":"off"},
+				{"id":2,"name":"packer-2","status":"active","size_slug":"s-1vcpu-1gb",
+				 "region":{"slug":"nyc3"},"image":{"id":42}}
+			]}`))
+		case r.Method == http.MethodDelete:
+			deleted = append(deleted, r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	client := godo.NewClient(nil)
+	client.BaseURL, _ = url.Parse(server.URL)
+
+	state := new(multistep.BasicStateBag)
+	state.Put("client", client)
+	state.Put("ui", packersdk.TestUi(t))
+	state.Put("config", &Config{Region: "nyc3", Size: "s-1vcpu-1gb", Image: "ubuntu-20-04-x64", Resume: true})
+
+	step := new(stepResumeDroplet)
+	action := step.Run(context.Background(), state)
+	require.Equal(t, multistep.ActionContinue, action, "%v", state.Get("error"))
+	require.Equal(t, []string{"/v2/droplets/1"}, deleted)
+	require.Equal(t, 2, state.Get("droplet_id"))
+	require.Equal(t, "nyc3", state.Get("build_region"))
+	require.Equal(t, "42", state.Get("source_image_id"))
+
+	// The droplet is kept when the build fails
+	state.Put(multistep.StateHalted, true)
+	step.Cleanup(state)
+	require.Equal(t, []string{"/v2/droplets/1"}, deleted)
+
+	// and destroyed once it succeeds
+	state.Remove(multistep.StateHalted)
+	step.Cleanup(state)
+	require.Equal(t, []string{"/v2/droplets/1", "/v2/droplets/2"}, deleted)
+}
@@ -19,12 +19,26 @@ func (s *stepShutdown) Run(ctx context.Context, state multistep.StateBag) multis
 	ui := state.Get("ui").(packersdk.Ui)
 	dropletId := state.Get("droplet_id").(int)
 
+	droplet, _, err := client.Droplets.Get(ctx, dropletId)
+	if err != nil {
+		err := fmt.Errorf("Error checking droplet state: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	if droplet.Status == "off" {
+		// Droplet is already off, which happens when snapshotting an
+		// existing droplet without provisioning it
+		return multistep.ActionContinue
+	}
+
 	// Gracefully power off the droplet. We have to retry this a number
 	// of times because sometimes it says it completed when it actually
 	// did absolutely nothing (*ALAKAZAM!* magic!). We give up after
 	// a pretty arbitrary amount of time.
 	ui.Say("Gracefully shutting down droplet...")
-	_, _, err := client.DropletActions.Shutdown(context.TODO(), dropletId)
+	_, _, err = client.DropletActions.Shutdown(ctx, dropletId)
 	if err != nil {
 		// If we get an error the first time, actually report it
 		err := fmt.Errorf("Error shutting down droplet: %s", err)
@@ -51,7 +65,7 @@ func (s *stepShutdown) Run(ctx context.Context, state multistep.StateBag) multis
 
 		for attempts := 2; attempts > 0; attempts++ {
 			log.Printf("ShutdownDroplet attempt #%d...", attempts)
-			_, _, err := client.DropletActions.Shutdown(context.TODO(), dropletId)
+			_, _, err := client.DropletActions.Shutdown(ctx, dropletId)
 			if err != nil {
 				log.Printf("Shutdown retry error: %s", err)
 			}
@@ -59,13 +73,15 @@ func (s *stepShutdown) Run(ctx context.Context, state multistep.StateBag) multis
 			select {
 			case <-done:
 				return
+			case <-ctx.Done():
+				return
 			case <-time.After(20 * time.Second):
 				// Retry!
 			}
 		}
 	}()
 
-	err = waitForDropletState("off", dropletId, client, c.StateTimeout)
+	err = waitForDropletState(ctx, "off", dropletId, client, c.PollInterval, c.StateTimeout)
 	if err != nil {
 		// If we get an error the first time, actually report it
 		err := fmt.Errorf("Error shutting down droplet: %s", err)
@@ -74,7 +90,7 @@ func (s *stepShutdown) Run(ctx context.Context, state multistep.StateBag) multis
 		return multistep.ActionHalt
 	}
 
-	if err := waitForDropletUnlocked(client, dropletId, c.StateTimeout); err != nil {
+	if err := waitForDropletUnlocked(ctx, client, dropletId, c.PollInterval, c.StateTimeout); err != nil {
 		// If we get an error the first time, actually report it
 		err := fmt.Errorf("Error shutting down droplet: %s", err)
 		state.Put("error", err)
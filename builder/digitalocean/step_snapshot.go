@@ -28,6 +28,19 @@ func (s *stepSnapshot) Run(ctx context.Context, state multistep.StateBag) multis
 	dropletId := state.Get("droplet_id").(int)
 	var snapshotRegions []string
 
+	// The region of an existing droplet is only known when the API
+	// returned it
+	buildRegion, ok := state.Get("build_region").(string)
+	if !ok || buildRegion == "" {
+		buildRegion = c.Region
+	}
+	if buildRegion == "" {
+		err := fmt.Errorf("Error creating snapshot: could not determine the region of droplet %d", dropletId)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
 	ui.Say(fmt.Sprintf("Creating snapshot: %v", c.SnapshotName))
 	action, _, err := client.DropletActions.Snapshot(context.TODO(), dropletId, c.SnapshotName)
 	if err != nil {
@@ -41,8 +54,8 @@ func (s *stepSnapshot) Run(ctx context.Context, state multistep.StateBag) multis
 	// because action can take a long time and may depend on the size of the final snapshot,
 	// the timeout is parameterized
 	ui.Say("Waiting for snapshot to complete...")
-	if err := waitForActionState(godo.ActionCompleted, dropletId, action.ID,
-		client, s.snapshotTimeout); err != nil {
+	if err := waitForActionState(ctx, godo.ActionCompleted, dropletId, action.ID,
+		client, c.PollInterval, s.snapshotTimeout); err != nil {
 		// If we get an error the first time, actually report it
 		err := fmt.Errorf("Error waiting for snapshot: %s", err)
 		state.Put("error", err)
@@ -53,7 +66,7 @@ func (s *stepSnapshot) Run(ctx context.Context, state multistep.StateBag) multis
 	// Wait for the droplet to become unlocked first. For snapshots
 	// this can end up taking quite a long time, so we hardcode this to
 	// 20 minutes.
-	if err := waitForDropletUnlocked(client, dropletId, 20*time.Minute); err != nil {
+	if err := waitForDropletUnlocked(ctx, client, dropletId, c.PollInterval, 20*time.Minute); err != nil {
 		// If we get an error the first time, actually report it
 		err := fmt.Errorf("Error shutting down droplet: %s", err)
 		state.Put("error", err)
@@ -121,7 +134,7 @@ func (s *stepSnapshot) Run(ctx context.Context, state multistep.StateBag) multis
 	if len(c.SnapshotRegions) > 0 {
 		regionSet := make(map[string]bool)
 		regions := make([]string, 0, len(c.SnapshotRegions))
-		regionSet[c.Region] = true
+		regionSet[buildRegion] = true
 		for _, region := range c.SnapshotRegions {
 			// If we already saw the region, then don't look again
 			if regionSet[region] {
@@ -151,10 +164,11 @@ func (s *stepSnapshot) Run(ctx context.Context, state multistep.StateBag) multis
 
 				if s.waitForSnapshotTransfer {
 					if err := WaitForImageState(
+						gCtx,
 						godo.ActionCompleted,
 						imageId,
 						imageTransfer.ID,
-						client, s.transferTimeout); err != nil {
+						client, c.PollInterval, s.transferTimeout); err != nil {
 						return fmt.Errorf("Error waiting for snapshot transfer: %s", err)
 					}
 					ui.Say(fmt.Sprintf("Transfer to %s is complete.", region))
@@ -171,7 +185,7 @@ func (s *stepSnapshot) Run(ctx context.Context, state multistep.StateBag) multis
 		}
 	}
 
-	snapshotRegions = append(snapshotRegions, c.Region)
+	snapshotRegions = append(snapshotRegions, buildRegion)
 
 	state.Put("snapshot_image_id", imageId)
 	state.Put("snapshot_name", c.SnapshotName)
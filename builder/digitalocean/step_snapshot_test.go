@@ -0,0 +1,67 @@
+package digitalocean
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStepSnapshot_BuildRegion(t *testing.T) {
+	tests := []struct {
+		name        string
+		buildRegion string
+		region      string
+		action      multistep.StepAction
+		regions     []string
+	}{
+		{name: "build region", buildRegion: "ams3", region: "nyc3", action: multistep.ActionContinue, regions: []string{"ams3"}},
+		{name: "config region", region: "nyc3", action: multistep.ActionContinue, regions: []string{"nyc3"}},
+		{name: "unknown region", action: multistep.ActionHalt},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			mux.HandleFunc("/v2/droplets/1234/actions", func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusCreated)
+				_, _ = w.Write([]byte(`{"action":{"id":5,"status":"in-progress"}}`))
+			})
+			mux.HandleFunc("/v2/droplets/1234/actions/5", func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte(`{"action":{"id":5,"status":"completed"}}`))
+			})
+			mux.HandleFunc("/v2/droplets/1234", func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte(`{"droplet":{"id":1234,"locked":false}}`))
+			})
+			mux.HandleFunc("/v2/droplets/1234/snapshots", func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte(`{"snapshots":[{"id":99,"name":"packer-snapshot"}]}`))
+			})
+			server := httptest.NewServer(mux)
+			defer server.Close()
+
+			client := godo.NewClient(nil)
+			client.BaseURL, _ = url.Parse(server.URL)
+
+			state := new(multistep.BasicStateBag)
+			state.Put("client", client)
+			state.Put("ui", packersdk.TestUi(t))
+			state.Put("config", &Config{SnapshotName: "packer-snapshot", Region: tt.region})
+			state.Put("droplet_id", 1234)
+			if tt.buildRegion != "" {
+				state.Put("build_region", tt.buildRegion)
+			}
+
+			action := new(stepSnapshot).Run(context.Background(), state)
+			require.Equal(t, tt.action, action, "%v", state.Get("error"))
+			if tt.regions != nil {
+				require.Equal(t, tt.regions, state.Get("regions"))
+			}
+		})
+	}
+}
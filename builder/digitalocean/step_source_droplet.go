@@ -0,0 +1,100 @@
+package digitalocean
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// stepSourceDroplet looks up the droplet set in source_droplet_id and uses it
+// for the rest of the build in place of stepCreateDroplet. The droplet
+// belongs to the user, so it is never destroyed.
+type stepSourceDroplet struct{}
+
+func (s *stepSourceDroplet) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	client := state.Get("client").(*godo.Client)
+	ui := state.Get("ui").(packersdk.Ui)
+	c := state.Get("config").(*Config)
+
+	ui.Say(fmt.Sprintf("Using existing droplet %d...", c.SourceDropletID))
+	droplet, _, err := client.Droplets.Get(ctx, c.SourceDropletID)
+	if err != nil {
+		err := fmt.Errorf("Error retrieving source droplet: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	// Store the source image ID and
+	// other miscellaneous info for HCP Packer
+	if droplet.Image != nil {
+		state.Put("source_image_id", strconv.Itoa(droplet.Image.ID))
+	}
+	state.Put("droplet_size", droplet.SizeSlug)
+	state.Put("droplet_name", droplet.Name)
+	if droplet.Region != nil {
+		state.Put("build_region", droplet.Region.Slug)
+	}
+
+	// Store the droplet id for later
+	state.Put("droplet_id", droplet.ID)
+	// instance_id is the generic term used so that users can have access to the
+	// instance id inside of the provisioners, used in step_provision.
+	state.Put("instance_id", droplet.ID)
+
+	// The droplet needs to be running for stepDropletInfo and the
+	// provisioners. Without a communicator, neither runs and it can stay
+	// off.
+	if droplet.Status == "off" && c.Comm.Type != "none" {
+		ui.Say("Powering on droplet...")
+		action, _, err := client.DropletActions.PowerOn(ctx, droplet.ID)
+		if err != nil {
+			err := fmt.Errorf("Error powering on droplet: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+
+		if err := waitForActionState(ctx, godo.ActionCompleted, droplet.ID, action.ID,
+			client, c.PollInterval, c.StateTimeout); err != nil {
+			err := fmt.Errorf("Error waiting for droplet to power on: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepSourceDroplet) Cleanup(state multistep.StateBag) {
+	c := state.Get("config").(*Config)
+	if !c.PowerOnAfterSnapshot {
+		return
+	}
+
+	client := state.Get("client").(*godo.Client)
+	ui := state.Get("ui").(packersdk.Ui)
+
+	droplet, _, err := client.Droplets.Get(context.TODO(), c.SourceDropletID)
+	if err != nil {
+		ui.Error(fmt.Sprintf(
+			"Error checking droplet state. Please power it on manually: %s", err))
+		return
+	}
+
+	if droplet.Status != "off" {
+		return
+	}
+
+	ui.Say("Powering droplet back on...")
+	_, _, err = client.DropletActions.PowerOn(context.TODO(), c.SourceDropletID)
+	if err != nil {
+		ui.Error(fmt.Sprintf(
+			"Error powering on droplet. Please power it on manually: %s", err))
+	}
+}
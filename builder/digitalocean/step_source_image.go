@@ -0,0 +1,40 @@
+package digitalocean
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/digitalocean/godo"
+	docommon "github.com/digitalocean/packer-plugin-digitalocean/common"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// stepSourceImage resolves image_filter to the ID of the image the droplet
+// is created from.
+type stepSourceImage struct{}
+
+func (s *stepSourceImage) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	client := state.Get("client").(*godo.Client)
+	ui := state.Get("ui").(packersdk.Ui)
+	c := state.Get("config").(*Config)
+
+	ui.Say("Looking up source image...")
+	image, err := docommon.FindImage(ctx, client, c.ImageFilter)
+	if err != nil {
+		err := fmt.Errorf("Error looking up source image: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	ui.Say(fmt.Sprintf("Found source image: %s (ID: %d)", image.Name, image.ID))
+	state.Put("source_image_id", strconv.Itoa(image.ID))
+
+	return multistep.ActionContinue
+}
+
+func (s *stepSourceImage) Cleanup(state multistep.StateBag) {
+	// no cleanup
+}
@@ -0,0 +1,96 @@
+package digitalocean
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
+)
+
+type volumeSnapshotNameData struct {
+	SnapshotName string
+	VolumeName   string
+}
+
+type stepVolumeSnapshots struct {
+	snapshotIds []string
+}
+
+func (s *stepVolumeSnapshots) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	c := state.Get("config").(*Config)
+	if !c.VolumeSnapshots {
+		return multistep.ActionContinue
+	}
+
+	client := state.Get("client").(*godo.Client)
+	ui := state.Get("ui").(packersdk.Ui)
+	volumeIds := state.Get("volume_ids").([]string)
+
+	for _, id := range volumeIds {
+		volume, _, err := client.Storage.GetVolume(ctx, id)
+		if err != nil {
+			err := fmt.Errorf("Error retrieving volume %s: %s", id, err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+
+		c.ctx.Data = &volumeSnapshotNameData{
+			SnapshotName: c.SnapshotName,
+			VolumeName:   volume.Name,
+		}
+		name, err := interpolate.Render(c.VolumeSnapshotName, &c.ctx)
+		if err != nil {
+			err := fmt.Errorf("Error rendering volume_snapshot_name template: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+
+		ui.Say(fmt.Sprintf("Creating volume snapshot: %v", name))
+		snapshot, _, err := client.Storage.CreateSnapshot(ctx, &godo.SnapshotCreateRequest{
+			VolumeID: id,
+			Name:     name,
+			Tags:     c.SnapshotTags,
+		})
+		if err != nil {
+			err := fmt.Errorf("Error creating volume snapshot: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+
+		// We use this in cleanup
+		s.snapshotIds = append(s.snapshotIds, snapshot.ID)
+
+		log.Printf("Volume snapshot ID: %s", snapshot.ID)
+	}
+
+	state.Put("volume_snapshot_ids", s.snapshotIds)
+
+	return multistep.ActionContinue
+}
+
+func (s *stepVolumeSnapshots) Cleanup(state multistep.StateBag) {
+	// The snapshots are the artifact of a successful build
+	_, cancelled := state.GetOk(multistep.StateCancelled)
+	_, halted := state.GetOk(multistep.StateHalted)
+	if len(s.snapshotIds) == 0 || (!cancelled && !halted) {
+		return
+	}
+
+	client := state.Get("client").(*godo.Client)
+	ui := state.Get("ui").(packersdk.Ui)
+
+	ui.Say("Deleting volume snapshots of the failed build...")
+	for _, id := range s.snapshotIds {
+		if _, err := client.Storage.DeleteSnapshot(context.TODO(), id); err != nil {
+			ui.Error(fmt.Sprintf(
+				"Error deleting volume snapshot %s. Please delete it manually: %s", id, err))
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package digitalocean
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStepVolumeSnapshots(t *testing.T) {
+	tests := []struct {
+		name        string
+		failVolume  string
+		cancelled   bool
+		action      multistep.StepAction
+		snapshotIds []string
+		deleted     []string
+	}{
+		{
+			name:        "success",
+			action:      multistep.ActionContinue,
+			snapshotIds: []string{"snap-vol-1", "snap-vol-2", "snap-vol-3"},
+		},
+		{
+			name:       "third snapshot fails",
+			failVolume: "vol-3",
+			action:     multistep.ActionHalt,
+			deleted:    []string{"snap-vol-1", "snap-vol-2"},
+		},
+		{
+			name:       "first snapshot fails",
+			failVolume: "vol-1",
+			action:     multistep.ActionHalt,
+		},
+		{
+			name:        "build cancelled",
+			cancelled:   true,
+			action:      multistep.ActionContinue,
+			snapshotIds: []string{"snap-vol-1", "snap-vol-2", "snap-vol-3"},
+			deleted:     []string{"snap-vol-1", "snap-vol-2", "snap-vol-3"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var deleted []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/v2/volumes/"):
+					id := strings.TrimPrefix(r.URL.Path, "/v2/volumes/")
+					fmt.Fprintf(w, `{"volume":{"id":%q,"name":"data-%s"}}`, id, id)
+				case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/snapshots"):
+					var req godo.SnapshotCreateRequest
+					require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+					if req.VolumeID == tt.failVolume {
+						w.WriteHeader(http.StatusUnprocessableEntity)
+						fmt.Fprint(w, `{"id":"unprocessable_entity","message":"snapshot limit reached"}`)
+						return
+					}
+					w.WriteHeader(http.StatusCreated)
+					fmt.Fprintf(w, `{"snapshot":{"id":"snap-%s","name":%q}}`, req.VolumeID, req.Name)
+				case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/v2/snapshots/"):
+					deleted = append(deleted, strings.TrimPrefix(r.URL.Path, "/v2/snapshots/"))
+					w.WriteHeader(http.StatusNoContent)
+				default:
+					t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+					w.WriteHeader(http.StatusInternalServerError)
+				}
+			}))
+			defer server.Close()
+
+			client := godo.NewClient(nil)
+			client.BaseURL, _ = url.Parse(server.URL)
+
+			state := new(multistep.BasicStateBag)
+			state.Put("client", client)
+			state.Put("ui", packersdk.TestUi(t))
+			state.Put("config", &Config{
+				SnapshotName:       "packer-snapshot",
+				VolumeSnapshots:    true,
+				VolumeSnapshotName: "{{ .SnapshotName }}-{{ .VolumeName }}",
+			})
+			state.Put("volume_ids", []string{"vol-1", "vol-2", "vol-3"})
+
+			step := new(stepVolumeSnapshots)
+			action := step.Run(context.Background(), state)
+			require.Equal(t, tt.action, action, "%v", state.Get("error"))
+			if tt.snapshotIds != nil {
+				require.Equal(t, tt.snapshotIds, state.Get("volume_snapshot_ids"))
+			}
+
+			if action == multistep.ActionHalt {
+				state.Put(multistep.StateHalted, true)
+			}
+			if tt.cancelled {
+				state.Put(multistep.StateCancelled, true)
+			}
+			step.Cleanup(state)
+			require.Equal(t, tt.deleted, deleted)
+		})
+	}
+}
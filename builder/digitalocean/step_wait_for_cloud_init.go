@@ -0,0 +1,140 @@
+package digitalocean
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	docommon "github.com/digitalocean/packer-plugin-digitalocean/common"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+const (
+	cloudInitStatusCommand = "cloud-init status --wait --long"
+	cloudInitResultCommand = "cat /var/lib/cloud/data/result.json"
+	cloudInitDescription   = "cloud-init to finish"
+)
+
+// stepWaitForCloudInit waits for cloud-init to finish processing the user
+// data before the droplet is provisioned, and fails the build if cloud-init
+// reports errors.
+type stepWaitForCloudInit struct {
+	timeout      time.Duration
+	pollInterval time.Duration
+}
+
+// cloudInitResult is the content of the result.json file cloud-init writes
+// once done.
+type cloudInitResult struct {
+	V1 struct {
+		Errors []string `json:"errors"`
+	} `json:"v1"`
+}
+
+func (s *stepWaitForCloudInit) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	comm := state.Get("communicator").(packersdk.Communicator)
+	ui := state.Get("ui").(packersdk.Ui)
+
+	ui.Say(fmt.Sprintf("Waiting for cloud-init to finish (timeout: %s)...", s.timeout))
+
+	if err := s.waitForCloudInit(ctx, comm, ui); err != nil {
+		err := fmt.Errorf("Error waiting for cloud-init: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	ui.Say("cloud-init finished")
+	return multistep.ActionContinue
+}
+
+func (s *stepWaitForCloudInit) Cleanup(state multistep.StateBag) {
+	// no cleanup
+}
+
+// waitForCloudInit runs `cloud-init status --wait`. Images with a cloud-init
+// too old to have the status command are polled for the result.json file
+// instead.
+func (s *stepWaitForCloudInit) waitForCloudInit(ctx context.Context, comm packersdk.Communicator, ui packersdk.Ui) error {
+	deadline := time.Now().Add(s.timeout)
+	statusCtx, cancel := context.WithDeadline(ctx, deadline)
+	defer cancel()
+
+	cmd := &packersdk.RemoteCmd{Command: cloudInitStatusCommand}
+	err := cmd.RunWithUi(statusCtx, comm, ui)
+	if ctx.Err() != nil {
+		return &docommon.CanceledError{Description: cloudInitDescription, Err: ctx.Err()}
+	}
+	if statusCtx.Err() != nil {
+		return &docommon.TimeoutError{Description: cloudInitDescription, Timeout: s.timeout}
+	}
+	if err != nil {
+		return err
+	}
+
+	switch status := cmd.ExitStatus(); status {
+	case 0:
+		return nil
+	case 2:
+		// cloud-init 23.4 and later exit with 2 on recoverable errors, such
+		// as deprecated configuration keys
+		ui.Message("cloud-init finished with recoverable errors")
+		return nil
+	case 127:
+		log.Printf("cloud-init status is not available, polling result.json")
+		return s.waitForResultFile(ctx, comm, time.Until(deadline))
+	default:
+		return fmt.Errorf("cloud-init reported errors (exit status %d)", status)
+	}
+}
+
+// waitForResultFile polls result.json until it exists and returns the errors
+// it reports.
+func (s *stepWaitForCloudInit) waitForResultFile(ctx context.Context, comm packersdk.Communicator, timeout time.Duration) error {
+	if timeout <= 0 {
+		return &docommon.TimeoutError{Description: cloudInitDescription, Timeout: s.timeout}
+	}
+
+	var result cloudInitResult
+	err := docommon.WaitFor(ctx, docommon.WaitOptions{
+		Description:  cloudInitDescription,
+		PollInterval: s.pollInterval,
+		Timeout:      timeout,
+	}, func(ctx context.Context) (bool, error) {
+		var stdout bytes.Buffer
+		cmd := &packersdk.RemoteCmd{
+			Command: cloudInitResultCommand,
+			Stdout:  &stdout,
+		}
+		if err := comm.Start(ctx, cmd); err != nil {
+			return false, err
+		}
+		if cmd.Wait() != 0 {
+			return false, nil
+		}
+
+		if err := json.Unmarshal(stdout.Bytes(), &result); err != nil {
+			return false, fmt.Errorf("could not parse result.json: %s", err)
+		}
+		return true, nil
+	})
+	var timeoutErr *docommon.TimeoutError
+	if errors.As(err, &timeoutErr) {
+		// Report the timeout of the whole wait, not of the polling
+		timeoutErr.Timeout = s.timeout
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(result.V1.Errors) > 0 {
+		return fmt.Errorf("cloud-init reported errors: %s", strings.Join(result.V1.Errors, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package digitalocean
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/stretchr/testify/require"
+)
+
+// cloudInitCommunicator answers the commands of stepWaitForCloudInit. Each
+// command pops the next response configured for it; the last one repeats.
+type cloudInitCommunicator struct {
+	packersdk.MockCommunicator
+
+	responses map[string][]cloudInitResponse
+	commands  []string
+}
+
+type cloudInitResponse struct {
+	exitStatus int
+	stdout     string
+}
+
+func (c *cloudInitCommunicator) Start(ctx context.Context, rc *packersdk.RemoteCmd) error {
+	c.commands = append(c.commands, rc.Command)
+
+	responses := c.responses[rc.Command]
+	response := cloudInitResponse{exitStatus: 127}
+	if len(responses) > 0 {
+		response = responses[0]
+		if len(responses) > 1 {
+			c.responses[rc.Command] = responses[1:]
+		}
+	}
+
+	go func() {
+		if rc.Stdout != nil && response.stdout != "" {
+			io.Copy(rc.Stdout, strings.NewReader(response.stdout))
+		}
+		rc.SetExited(response.exitStatus)
+	}()
+
+	return nil
+}
+
+func TestStepWaitForCloudInit(t *testing.T) {
+	tests := []struct {
+		name      string
+		responses map[string][]cloudInitResponse
+		action    multistep.StepAction
+		commands  []string
+	}{
+		{
+			name: "done",
+			responses: map[string][]cloudInitResponse{
+				cloudInitStatusCommand: {{exitStatus: 0, stdout: "status: done\n"}},
+			},
+			action:   multistep.ActionContinue,
+			commands: []string{cloudInitStatusCommand},
+		},
+		{
+			name: "recoverable errors",
+			responses: map[string][]cloudInitResponse{
+				cloudInitStatusCommand: {{exitStatus: 2, stdout: "status: done\n"}},
+			},
+			action:   multistep.ActionContinue,
+			commands: []string{cloudInitStatusCommand},
+		},
+		{
+			name: "errors",
+			responses: map[string][]cloudInitResponse{
+				cloudInitStatusCommand: {{exitStatus: 1, stdout: "status: error\n"}},
+			},
+			action:   multistep.ActionHalt,
+			commands: []string{cloudInitStatusCommand},
+		},
+		{
+			name: "result file",
+			responses: map[string][]cloudInitResponse{
+				cloudInitResultCommand: {
+					{exitStatus: 1},
+					{exitStatus: 0, stdout: `{"v1": {"datasource": "DataSourceDigitalOcean", "errors": []}}`},
+				},
+			},
+			action:   multistep.ActionContinue,
+			commands: []string{cloudInitStatusCommand, cloudInitResultCommand, cloudInitResultCommand},
+		},
+		{
+			name: "result file with errors",
+			responses: map[string][]cloudInitResponse{
+				cloudInitResultCommand: {
+					{exitStatus: 0, stdout: `{"v1": {"errors": ["failed to run scripts-user"]}}`},
+				},
+			},
+			action:   multistep.ActionHalt,
+			commands: []string{cloudInitStatusCommand, cloudInitResultCommand},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			comm := &cloudInitCommunicator{responses: tt.responses}
+			state := new(multistep.BasicStateBag)
+			state.Put("communicator", comm)
+			state.Put("ui", packersdk.TestUi(t))
+
+			step := &stepWaitForCloudInit{timeout: time.Minute, pollInterval: time.Millisecond}
+			action := step.Run(context.Background(), state)
+
+			require.Equal(t, tt.action, action)
+			require.Equal(t, tt.commands, comm.commands)
+			_, hasError := state.GetOk("error")
+			require.Equal(t, tt.action == multistep.ActionHalt, hasError)
+		})
+	}
+}
+
+func TestStepWaitForCloudInit_Timeout(t *testing.T) {
+	comm := &cloudInitCommunicator{
+		responses: map[string][]cloudInitResponse{
+			cloudInitResultCommand: {{exitStatus: 1}},
+		},
+	}
+	state := new(multistep.BasicStateBag)
+	state.Put("communicator", comm)
+	state.Put("ui", packersdk.TestUi(t))
+
+	step := &stepWaitForCloudInit{timeout: 50 * time.Millisecond, pollInterval: 10 * time.Millisecond}
+	action := step.Run(context.Background(), state)
+
+	require.Equal(t, multistep.ActionHalt, action)
+	require.Contains(t, state.Get("error").(error).Error(), "Timeout after 50ms while waiting for cloud-init to finish")
+}
+
+func TestStepWaitForCloudInit_Cancel(t *testing.T) {
+	comm := &cloudInitCommunicator{
+		responses: map[string][]cloudInitResponse{
+			cloudInitResultCommand: {{exitStatus: 1}},
+		},
+	}
+	state := new(multistep.BasicStateBag)
+	state.Put("communicator", comm)
+	state.Put("ui", packersdk.TestUi(t))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	step := &stepWaitForCloudInit{timeout: time.Minute, pollInterval: 10 * time.Millisecond}
+	action := step.Run(ctx, state)
+
+	require.Equal(t, multistep.ActionHalt, action)
+	require.Contains(t, state.Get("error").(error).Error(), "Canceled while waiting for cloud-init to finish")
+}
@@ -0,0 +1,140 @@
+package digitalocean
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/textproto"
+	"strings"
+)
+
+// userDataTemplateData is the data available when rendering user_data.
+type userDataTemplateData struct {
+	DropletName string
+	BuildName   string
+}
+
+// userDataPart is one part of a MIME multi-part user data document.
+type userDataPart struct {
+	contentType string
+	mergeType   string
+	content     string
+}
+
+// userDataContentTypes maps the first line prefixes understood by cloud-init
+// and by cloudbase-init, for Windows, to the content type of the part.
+var userDataContentTypes = []struct {
+	prefix      string
+	contentType string
+}{
+	{"#cloud-config-archive", "text/cloud-config-archive"},
+	{"#cloud-config", "text/cloud-config"},
+	{"#cloud-boothook", "text/cloud-boothook"},
+	{"#include", "text/x-include-url"},
+	{"#part-handler", "text/part-handler"},
+	{"#upstart-job", "text/upstart-job"},
+	{"#!", "text/x-shellscript"},
+	{"#ps1", "text/x-shellscript"},
+}
+
+// detectUserDataContentType returns the content type cloud-init would infer
+// for the user data.
+func detectUserDataContentType(content string) string {
+	for _, t := range userDataContentTypes {
+		if strings.HasPrefix(content, t.prefix) {
+			return t.contentType
+		}
+	}
+
+	return "text/plain"
+}
+
+// isMultipartUserData reports whether the user data already is a MIME
+// multi-part document.
+func isMultipartUserData(content string) bool {
+	for _, line := range strings.SplitN(content, "\n", 10) {
+		if strings.HasPrefix(strings.ToLower(line), "content-type: multipart/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// sshKeyCloudConfig returns a cloud-config document authorizing the public
+// key for the default user.
+func sshKeyCloudConfig(publicKey []byte) string {
+	return fmt.Sprintf("#cloud-config\nssh_authorized_keys:\n  - %s\n",
+		strings.TrimSpace(string(publicKey)))
+}
+
+// newUserDataPart returns a part with the content type cloud-init would
+// infer for the content.
+func newUserDataPart(content string) userDataPart {
+	return userDataPart{
+		contentType: detectUserDataContentType(content),
+		content:     content,
+	}
+}
+
+// sshKeyUserDataPart returns a cloud-config part authorizing the public key,
+// merged into any `ssh_authorized_keys` of the other parts.
+func sshKeyUserDataPart(publicKey []byte) userDataPart {
+	return userDataPart{
+		contentType: "text/cloud-config",
+		mergeType:   "list(append)+dict(no_replace,recurse_list)+str()",
+		content:     sshKeyCloudConfig(publicKey),
+	}
+}
+
+// combineUserData returns the user data made of the parts. A single part is
+// returned verbatim, several parts are combined in a MIME multi-part
+// document.
+func combineUserData(parts []userDataPart) (string, error) {
+	switch len(parts) {
+	case 0:
+		return "", nil
+	case 1:
+		return parts[0].content, nil
+	}
+
+	for _, part := range parts {
+		if isMultipartUserData(part.content) {
+			return "", errors.New("MIME multi-part user data can not be combined with other user data")
+		}
+	}
+
+	return buildMultipartUserData(parts)
+}
+
+// buildMultipartUserData returns a MIME multi-part document with the parts,
+// in order.
+func buildMultipartUserData(parts []userDataPart) (string, error) {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+
+	for _, part := range parts {
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Type", fmt.Sprintf("%s; charset=\"utf-8\"", part.contentType))
+		header.Set("MIME-Version", "1.0")
+		if part.mergeType != "" {
+			header.Set("Merge-Type", part.mergeType)
+		}
+
+		pw, err := w.CreatePart(header)
+		if err != nil {
+			return "", err
+		}
+		if _, err := pw.Write([]byte(part.content)); err != nil {
+			return "", err
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("Content-Type: multipart/mixed; boundary=\"%s\"\nMIME-Version: 1.0\n\n%s",
+		w.Boundary(), body.String()), nil
+}
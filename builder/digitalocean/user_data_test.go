@@ -0,0 +1,150 @@
+package digitalocean
+
+import (
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testPublicKey = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIFakeKey packer\n"
+
+// readMultipartUserData returns the content types and contents of the parts
+// of a MIME multi-part user data document.
+func readMultipartUserData(t *testing.T, userData string) ([]string, []string) {
+	msg, err := mail.ReadMessage(strings.NewReader(userData))
+	require.NoError(t, err)
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	require.Equal(t, "multipart/mixed", mediaType)
+
+	var types, contents []string
+	r := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := r.NextPart()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+
+		contentType, _, err := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		require.NoError(t, err)
+		content, err := io.ReadAll(part)
+		require.NoError(t, err)
+
+		types = append(types, contentType)
+		contents = append(contents, string(content))
+	}
+
+	return types, contents
+}
+
+func TestDetectUserDataContentType(t *testing.T) {
+	tests := map[string]string{
+		"#cloud-config\npackages: [nginx]": "text/cloud-config",
+		"#!/bin/bash\necho hello":          "text/x-shellscript",
+		"#cloud-boothook\necho hello":      "text/cloud-boothook",
+		"#include\nhttps://example.com":    "text/x-include-url",
+		"hello":                            "text/plain",
+	}
+
+	for content, expected := range tests {
+		require.Equal(t, expected, detectUserDataContentType(content), content)
+	}
+}
+
+func TestCombineUserData(t *testing.T) {
+	t.Run("without parts", func(t *testing.T) {
+		userData, err := combineUserData(nil)
+		require.NoError(t, err)
+		require.Equal(t, "", userData)
+	})
+
+	t.Run("with the SSH key only", func(t *testing.T) {
+		userData, err := combineUserData([]userDataPart{sshKeyUserDataPart([]byte(testPublicKey))})
+		require.NoError(t, err)
+		require.Equal(t, "#cloud-config\nssh_authorized_keys:\n  - ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIFakeKey packer\n", userData)
+	})
+
+	t.Run("with a shell script and the SSH key", func(t *testing.T) {
+		userData, err := combineUserData([]userDataPart{
+			newUserDataPart("#!/bin/bash\necho hello\n"),
+			sshKeyUserDataPart([]byte(testPublicKey)),
+		})
+		require.NoError(t, err)
+
+		types, contents := readMultipartUserData(t, userData)
+		require.Equal(t, []string{"text/x-shellscript", "text/cloud-config"}, types)
+		require.Equal(t, "#!/bin/bash\necho hello\n", contents[0])
+		require.Equal(t, sshKeyCloudConfig([]byte(testPublicKey)), contents[1])
+	})
+
+	t.Run("with multi-part user data", func(t *testing.T) {
+		_, err := combineUserData([]userDataPart{
+			newUserDataPart("Content-Type: multipart/mixed; boundary=\"x\"\n\n--x--\n"),
+			sshKeyUserDataPart([]byte(testPublicKey)),
+		})
+		require.Error(t, err)
+	})
+
+	t.Run("with multi-part user data only", func(t *testing.T) {
+		multi := "Content-Type: multipart/mixed; boundary=\"x\"\n\n--x--\n"
+		userData, err := combineUserData([]userDataPart{newUserDataPart(multi)})
+		require.NoError(t, err)
+		require.Equal(t, multi, userData)
+	})
+}
+
+func TestStepCreateDroplet_buildUserData(t *testing.T) {
+	dir := t.TempDir()
+	cloudConfig := filepath.Join(dir, "cloud-config.yaml")
+	require.NoError(t, os.WriteFile(cloudConfig, []byte("#cloud-config\npackages: [nginx]\n"), 0644))
+	script := filepath.Join(dir, "script.sh")
+	require.NoError(t, os.WriteFile(script, []byte("#!/bin/sh\necho {{ .DropletName }}\n"), 0644))
+
+	t.Run("renders user_data", func(t *testing.T) {
+		c := &Config{
+			DropletName: "packer-build",
+			UserData:    "#!/bin/sh\nhostname {{ .DropletName }}-{{ .BuildName }}\n",
+		}
+		c.PackerBuildName = "web"
+
+		userData, err := new(stepCreateDroplet).buildUserData(c)
+		require.NoError(t, err)
+		require.Equal(t, "#!/bin/sh\nhostname packer-build-web\n", userData)
+	})
+
+	t.Run("combines files and user_data in order", func(t *testing.T) {
+		c := &Config{
+			DropletName:   "packer-build",
+			UserDataFile:  cloudConfig,
+			UserDataFiles: []string{script},
+			UserData:      "#!/bin/sh\necho {{ .DropletName }}\n",
+		}
+
+		userData, err := new(stepCreateDroplet).buildUserData(c)
+		require.NoError(t, err)
+
+		types, contents := readMultipartUserData(t, userData)
+		require.Equal(t, []string{"text/cloud-config", "text/x-shellscript", "text/x-shellscript"}, types)
+		require.Equal(t, []string{
+			"#cloud-config\npackages: [nginx]\n",
+			"#!/bin/sh\necho {{ .DropletName }}\n",
+			"#!/bin/sh\necho packer-build\n",
+		}, contents)
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		c := &Config{UserDataFiles: []string{filepath.Join(dir, "missing")}}
+
+		_, err := new(stepCreateDroplet).buildUserData(c)
+		require.Error(t, err)
+	})
+}
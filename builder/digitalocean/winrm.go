@@ -0,0 +1,79 @@
+package digitalocean
+
+import (
+	"bytes"
+	"crypto/rand"
+	"math/big"
+	"strings"
+	"text/template"
+)
+
+// winrmPasswordScript is run by cloudbase-init on the first boot of Windows
+// droplets. It sets the password of the WinRM user and enables WinRM with
+// basic authentication.
+var winrmPasswordScript = template.Must(template.New("winrm").Funcs(template.FuncMap{
+	"quote": func(s string) string {
+		return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+	},
+}).Parse(`#ps1_sysnative
+$ErrorActionPreference = "Stop"
+
+$password = ConvertTo-SecureString {{ quote .Password }} -AsPlainText -Force
+Set-LocalUser -Name {{ quote .Username }} -Password $password
+Enable-LocalUser -Name {{ quote .Username }}
+
+Enable-PSRemoting -Force -SkipNetworkProfileCheck
+Set-Item -Path WSMan:\localhost\Service\Auth\Basic -Value $true
+{{- if .UseSSL }}
+$cert = New-SelfSignedCertificate -DnsName $env:COMPUTERNAME -CertStoreLocation Cert:\LocalMachine\My
+New-Item -Path WSMan:\localhost\Listener -Transport HTTPS -Address * -CertificateThumbPrint $cert.Thumbprint -Force
+{{- else }}
+Set-Item -Path WSMan:\localhost\Service\AllowUnencrypted -Value $true
+{{- end }}
+New-NetFirewallRule -DisplayName "Packer WinRM" -Direction Inbound -Protocol TCP -LocalPort {{ .Port }} -Action Allow
+Restart-Service -Name WinRM
+`))
+
+// winrmPasswordUserData returns the PowerShell script setting the password of
+// the user and enabling WinRM on the port.
+func winrmPasswordUserData(username, password string, port int, useSSL bool) (string, error) {
+	var buf bytes.Buffer
+	err := winrmPasswordScript.Execute(&buf, struct {
+		Username string
+		Password string
+		Port     int
+		UseSSL   bool
+	}{username, password, port, useSSL})
+	if err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+const (
+	winrmPasswordLength   = 24
+	winrmPasswordAlphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_.+"
+)
+
+// generateWinRMPassword returns a random password meeting the default
+// Windows complexity requirements.
+func generateWinRMPassword() (string, error) {
+	for {
+		b := make([]byte, winrmPasswordLength)
+		for i := range b {
+			n, err := rand.Int(rand.Reader, big.NewInt(int64(len(winrmPasswordAlphabet))))
+			if err != nil {
+				return "", err
+			}
+			b[i] = winrmPasswordAlphabet[n.Int64()]
+		}
+
+		password := string(b)
+		if strings.ContainsAny(password, "abcdefghijklmnopqrstuvwxyz") &&
+			strings.ContainsAny(password, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") &&
+			strings.ContainsAny(password, "0123456789") {
+			return password, nil
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package digitalocean
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/packer-plugin-sdk/communicator"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWinRMPasswordUserData(t *testing.T) {
+	script, err := winrmPasswordUserData("Administrator", "it's-a-secret", 5985, false)
+	require.NoError(t, err)
+	require.Equal(t, "text/x-shellscript", detectUserDataContentType(script))
+	require.Contains(t, script, "ConvertTo-SecureString 'it''s-a-secret'")
+	require.Contains(t, script, "Set-LocalUser -Name 'Administrator'")
+	require.Contains(t, script, "AllowUnencrypted -Value $true")
+	require.Contains(t, script, "-LocalPort 5985")
+	require.NotContains(t, script, "Transport HTTPS")
+
+	script, err = winrmPasswordUserData("Administrator", "secret", 5986, true)
+	require.NoError(t, err)
+	require.Contains(t, script, "Transport HTTPS")
+	require.Contains(t, script, "-LocalPort 5986")
+	require.NotContains(t, script, "AllowUnencrypted")
+}
+
+func TestGenerateWinRMPassword(t *testing.T) {
+	password, err := generateWinRMPassword()
+	require.NoError(t, err)
+	require.Len(t, password, winrmPasswordLength)
+	require.Regexp(t, "[a-z]", password)
+	require.Regexp(t, "[A-Z]", password)
+	require.Regexp(t, "[0-9]", password)
+
+	other, err := generateWinRMPassword()
+	require.NoError(t, err)
+	require.NotEqual(t, password, other)
+}
+
+func TestBuilder_WinRM(t *testing.T) {
+	var created struct {
+		SSHKeys  []interface{} `json:"ssh_keys"`
+		UserData string        `json:"user_data"`
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v2/droplets", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&created))
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"droplet":{"id":1234}}`))
+	})
+	mux.HandleFunc("/v2/droplets/1234", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"droplet":{"id":1234,"status":"active","networks":{"v4":[
+			{"ip_address":"10.0.0.2","type":"private"},
+			{"ip_address":"203.0.113.10","type":"public"}
+		]}}}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	var b Builder
+	config := testConfig()
+	delete(config, "ssh_username")
+	config["communicator"] = "winrm"
+	config["winrm_user_data_password"] = true
+	_, warnings, err := b.Prepare(config)
+	require.NoError(t, err)
+	require.Empty(t, warnings)
+	require.Equal(t, "Administrator", b.config.Comm.WinRMUser)
+	require.NotEmpty(t, b.config.Comm.WinRMPassword)
+
+	client := godo.NewClient(nil)
+	client.BaseURL, _ = url.Parse(server.URL)
+
+	state := new(multistep.BasicStateBag)
+	state.Put("client", client)
+	state.Put("ui", packersdk.TestUi(t))
+	state.Put("config", &b.config)
+
+	for _, step := range []multistep.Step{new(stepCreateDroplet), new(stepDropletInfo)} {
+		action := step.Run(context.Background(), state)
+		require.Equal(t, multistep.ActionContinue, action, "%v", state.Get("error"))
+	}
+
+	require.Empty(t, created.SSHKeys)
+	require.True(t, strings.HasPrefix(created.UserData, "#ps1_sysnative\n"))
+	require.Contains(t, created.UserData, b.config.Comm.WinRMPassword)
+
+	host, err := communicator.CommHost(b.config.Comm.Host(), "droplet_ip")(state)
+	require.NoError(t, err)
+	require.Equal(t, "203.0.113.10", host)
+}
@@ -0,0 +1,145 @@
+//go:generate packer-sdc struct-markdown
+
+// The common package contains configuration and helpers shared by the
+// DigitalOcean builder, data sources, and post-processors.
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"strconv"
+
+	"github.com/digitalocean/godo"
+	"github.com/digitalocean/packer-plugin-digitalocean/version"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/useragent"
+	"golang.org/x/oauth2"
+)
+
+// AccessConfig holds the options used to authenticate against and
+// communicate with the DigitalOcean API. It is meant to be embedded with
+// `mapstructure:",squash"` so that every component accepts the same options.
+type AccessConfig struct {
+	// The client TOKEN to use to access your account. It
+	// can also be specified via environment variable DIGITALOCEAN_TOKEN, DIGITALOCEAN_ACCESS_TOKEN, or DIGITALOCEAN_API_TOKEN if
+	// set. DIGITALOCEAN_API_TOKEN will be deprecated in a future release in favor of DIGITALOCEAN_TOKEN or DIGITALOCEAN_ACCESS_TOKEN.
+	APIToken string `mapstructure:"api_token" required:"true"`
+	// Non standard api endpoint URL. Set this if you are
+	// using a DigitalOcean API compatible service. It can also be specified via
+	// environment variable DIGITALOCEAN_API_URL.
+	APIURL string `mapstructure:"api_url" required:"false"`
+	// The maximum number of retries for requests that fail with a 429 or 500-level error.
+	// The default value is 5. Set to 0 to disable reties.
+	HTTPRetryMax *int `mapstructure:"http_retry_max" required:"false"`
+	// The maximum wait time (in seconds) between failed API requests. Default: 30.0
+	HTTPRetryWaitMax *float64 `mapstructure:"http_retry_wait_max" required:"false"`
+	// The minimum wait time (in seconds) between failed API requests. Default: 1.0
+	HTTPRetryWaitMin *float64 `mapstructure:"http_retry_wait_min" required:"false"`
+}
+
+// Prepare fills in defaults from the environment and validates the access
+// configuration. It returns any warnings along with the errors found.
+func (c *AccessConfig) Prepare() ([]string, []error) {
+	var warns []string
+	var errs []error
+
+	if c.APIToken == "" {
+		// Default to environment variable for api_token, if it exists
+		c.APIToken = os.Getenv("DIGITALOCEAN_TOKEN")
+		if c.APIToken == "" {
+			c.APIToken = os.Getenv("DIGITALOCEAN_ACCESS_TOKEN")
+		}
+		if c.APIToken == "" {
+			c.APIToken = os.Getenv("DIGITALOCEAN_API_TOKEN")
+			if c.APIToken != "" {
+				warns = append(warns, "The DIGITALOCEAN_API_TOKEN environment variable is deprecated "+
+					"and will produce an error in future versions of the DigitalOcean Packer plugin. "+
+					"Please use either DIGITALOCEAN_TOKEN or DIGITALOCEAN_ACCESS_TOKEN moving forward.")
+			}
+		}
+	}
+	if c.APIURL == "" {
+		c.APIURL = os.Getenv("DIGITALOCEAN_API_URL")
+	}
+	if c.HTTPRetryMax == nil {
+		c.HTTPRetryMax = godo.PtrTo(5)
+		if max := os.Getenv("DIGITALOCEAN_HTTP_RETRY_MAX"); max != "" {
+			maxInt, err := strconv.Atoi(max)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("invalid DIGITALOCEAN_HTTP_RETRY_MAX: %s", err))
+			} else {
+				c.HTTPRetryMax = godo.PtrTo(maxInt)
+			}
+		}
+	}
+	if c.HTTPRetryWaitMax == nil {
+		c.HTTPRetryWaitMax = godo.PtrTo(30.0)
+		if waitMax := os.Getenv("DIGITALOCEAN_HTTP_RETRY_WAIT_MAX"); waitMax != "" {
+			waitMaxFloat, err := strconv.ParseFloat(waitMax, 64)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("invalid DIGITALOCEAN_HTTP_RETRY_WAIT_MAX: %s", err))
+			} else {
+				c.HTTPRetryWaitMax = godo.PtrTo(waitMaxFloat)
+			}
+		}
+	}
+	if c.HTTPRetryWaitMin == nil {
+		c.HTTPRetryWaitMin = godo.PtrTo(1.0)
+		if waitMin := os.Getenv("DIGITALOCEAN_HTTP_RETRY_WAIT_MIN"); waitMin != "" {
+			waitMinFloat, err := strconv.ParseFloat(waitMin, 64)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("invalid DIGITALOCEAN_HTTP_RETRY_WAIT_MIN: %s", err))
+			} else {
+				c.HTTPRetryWaitMin = godo.PtrTo(waitMinFloat)
+			}
+		}
+	}
+
+	if c.APIToken == "" {
+		errs = append(errs, errors.New("api_token is required"))
+	}
+
+	if c.APIURL != "" {
+		if _, err := url.Parse(c.APIURL); err != nil {
+			errs = append(errs, fmt.Errorf("invalid api_url: %s", err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return warns, errs
+	}
+
+	packersdk.LogSecretFilter.Set(c.APIToken)
+	return warns, nil
+}
+
+// Client returns a DigitalOcean API client configured with the token,
+// endpoint, and retry options from the access configuration.
+func (c *AccessConfig) Client() (*godo.Client, error) {
+	ua := useragent.String(version.PluginVersion.FormattedVersion())
+	opts := []godo.ClientOpt{godo.SetUserAgent(ua)}
+	if c.APIURL != "" {
+		_, err := url.Parse(c.APIURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid API URL, %s", err)
+		}
+
+		opts = append(opts, godo.SetBaseURL(c.APIURL))
+	}
+	if c.HTTPRetryMax != nil && *c.HTTPRetryMax > 0 {
+		opts = append(opts, godo.WithRetryAndBackoffs(godo.RetryConfig{
+			RetryMax:     *c.HTTPRetryMax,
+			RetryWaitMin: c.HTTPRetryWaitMin,
+			RetryWaitMax: c.HTTPRetryWaitMax,
+			Logger:       log.Default(),
+		}))
+	}
+
+	return godo.New(oauth2.NewClient(context.TODO(), &APITokenSource{
+		AccessToken: c.APIToken,
+	}), opts...)
+}
@@ -0,0 +1,97 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/digitalocean/godo"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccessConfig_Prepare(t *testing.T) {
+	tests := []struct {
+		name          string
+		config        AccessConfig
+		env           map[string]string
+		expectedToken string
+		expectedWarns int
+		expectedError string
+	}{
+		{
+			name:          "token set in config",
+			config:        AccessConfig{APIToken: "config-token"},
+			env:           map[string]string{"DIGITALOCEAN_TOKEN": "env-token"},
+			expectedToken: "config-token",
+		},
+		{
+			name:          "DIGITALOCEAN_TOKEN",
+			env:           map[string]string{"DIGITALOCEAN_TOKEN": "env-token"},
+			expectedToken: "env-token",
+		},
+		{
+			name:          "DIGITALOCEAN_ACCESS_TOKEN",
+			env:           map[string]string{"DIGITALOCEAN_ACCESS_TOKEN": "env-token"},
+			expectedToken: "env-token",
+		},
+		{
+			name:          "DIGITALOCEAN_API_TOKEN is deprecated",
+			env:           map[string]string{"DIGITALOCEAN_API_TOKEN": "env-token"},
+			expectedToken: "env-token",
+			expectedWarns: 1,
+		},
+		{
+			name:          "no token",
+			expectedError: "api_token is required",
+		},
+		{
+			name:          "invalid retry max",
+			config:        AccessConfig{APIToken: "config-token"},
+			env:           map[string]string{"DIGITALOCEAN_HTTP_RETRY_MAX": "many"},
+			expectedToken: "config-token",
+			expectedError: "invalid DIGITALOCEAN_HTTP_RETRY_MAX",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{
+				"DIGITALOCEAN_TOKEN",
+				"DIGITALOCEAN_ACCESS_TOKEN",
+				"DIGITALOCEAN_API_TOKEN",
+				"DIGITALOCEAN_API_URL",
+				"DIGITALOCEAN_HTTP_RETRY_MAX",
+				"DIGITALOCEAN_HTTP_RETRY_WAIT_MAX",
+				"DIGITALOCEAN_HTTP_RETRY_WAIT_MIN",
+			} {
+				t.Setenv(key, tt.env[key])
+			}
+
+			c := tt.config
+			warns, errs := c.Prepare()
+			require.Len(t, warns, tt.expectedWarns)
+			require.Equal(t, tt.expectedToken, c.APIToken)
+
+			if tt.expectedError != "" {
+				require.Len(t, errs, 1)
+				require.Contains(t, errs[0].Error(), tt.expectedError)
+				return
+			}
+
+			require.Empty(t, errs)
+			require.Equal(t, 5, *c.HTTPRetryMax)
+			require.Equal(t, 30.0, *c.HTTPRetryWaitMax)
+			require.Equal(t, 1.0, *c.HTTPRetryWaitMin)
+		})
+	}
+}
+
+func TestAccessConfig_Client(t *testing.T) {
+	c := AccessConfig{
+		APIToken:     "token",
+		APIURL:       "https://api.example.com/",
+		HTTPRetryMax: godo.PtrTo(0),
+	}
+
+	client, err := c.Client()
+	require.NoError(t, err)
+	require.Equal(t, "https://api.example.com/", client.BaseURL.String())
+}
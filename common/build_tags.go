@@ -0,0 +1,42 @@
+package common
+
+import (
+	"regexp"
+	"strconv"
+	"time"
+)
+
+const (
+	// BuildTag is applied to every temporary droplet and volume created by
+	// the builder, so that resources left over from crashed builds can be
+	// found and removed.
+	BuildTag = "packer-build"
+)
+
+// resourceNameRe matches the `packer-<time-ordered-uuid>` names given to
+// temporary resources that can not be tagged, such as SSH keys and
+// firewalls. The first 8 hex digits of the UUID are the creation time.
+var resourceNameRe = regexp.MustCompile(`^packer-([0-9a-f]{8})-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+
+// BuildTags returns the tags marking a temporary resource as owned by
+// Packer. They are the same for every build, so no tag is left behind once
+// the resource is removed; the creation time is read from the resource.
+func BuildTags() []string {
+	return []string{BuildTag}
+}
+
+// ParseResourceName returns the creation time of a temporary resource
+// named `packer-<time-ordered-uuid>`, and false for any other name.
+func ParseResourceName(name string) (time.Time, bool) {
+	m := resourceNameRe.FindStringSubmatch(name)
+	if m == nil {
+		return time.Time{}, false
+	}
+
+	unix, err := strconv.ParseUint(m[1], 16, 32)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return time.Unix(int64(unix), 0).UTC(), true
+}
@@ -0,0 +1,23 @@
+package common
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildTags(t *testing.T) {
+	require.Equal(t, []string{"packer-build"}, BuildTags())
+}
+
+func TestParseResourceName(t *testing.T) {
+	created, ok := ParseResourceName("packer-62f423ca-1c8e-5b2f-9d3a-0a1b2c3d4e5f")
+	require.True(t, ok)
+	require.Equal(t, time.Date(2022, 8, 10, 21, 31, 54, 0, time.UTC), created)
+
+	for _, name := range []string{"packer", "packer-key", "my-packer-62f423ca-1c8e-5b2f-9d3a-0a1b2c3d4e5f"} {
+		_, ok := ParseResourceName(name)
+		require.False(t, ok, name)
+	}
+}
@@ -0,0 +1,352 @@
+//go:generate packer-sdc struct-markdown
+//go:generate packer-sdc mapstructure-to-hcl2 -type ImageFilterOptions
+
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/digitalocean/godo"
+)
+
+var (
+	validImageTypes  = []string{"application", "distribution", "user"}
+	validImageSortBy = []string{"created", "name", "size"}
+
+	// timeNow is stubbed by tests to make max_age filtering deterministic.
+	timeNow = time.Now
+)
+
+// ImageFilterOptions selects a single image from the images available to the
+// account. It is shared by the image data source and the builder's
+// `image_filter` block.
+type ImageFilterOptions struct {
+	// The name of the image to return. Only one of `name` or `name_regex` may be provided.
+	Name string `mapstructure:"name"`
+	// A regex matching the name of the image to return. Only one of `name` or `name_regex` may be provided.
+	NameRegex string `mapstructure:"name_regex"`
+	// Filter the images searched by type. This may be one of `application`, `distribution`, or `user`.
+	// By default, all image types are searched.
+	Type string `mapstructure:"type"`
+	// A DigitalOcean region slug (e.g. `nyc3`). When provided, only images available in that region
+	// will be returned.
+	Region string `mapstructure:"region"`
+	// A boolean value determining how to handle multiple matching images. By default, multiple matching images
+	// results in an error. When set to `true`, the first image according to `sort_by` is returned instead,
+	// which is the most recently created image by default.
+	Latest bool `mapstructure:"latest"`
+	// A list of tags the image must have. All of the tags must match. Only images of type `user` can be
+	// tagged, and the tag-scoped image listing is used so that other images are not searched.
+	Tags []string `mapstructure:"tags"`
+	// The name of the distribution of the image (e.g. `Ubuntu`).
+	Distribution string `mapstructure:"distribution"`
+	// The status of the image (e.g. `available`).
+	Status string `mapstructure:"status"`
+	// An RFC 3339 timestamp (e.g. `2024-01-02T15:04:05Z`). Only images created at or after this time are
+	// returned.
+	MinCreatedAt string `mapstructure:"min_created_at"`
+	// Only return images created within this duration (e.g. `720h`).
+	MaxAge time.Duration `mapstructure:"max_age"`
+	// Only return images whose `min_disk_size` is at most this value in GB, i.e. images that fit on a
+	// droplet with a disk of this size.
+	MinDiskSize int `mapstructure:"min_disk_size"`
+	// How to order multiple matching images when `latest` is set. One of `created` (the most recently
+	// created image first), `name` (the last name in lexical order first) or `size` (the largest image
+	// first). Defaults to `created`.
+	SortBy string `mapstructure:"sort_by"`
+}
+
+// Prepare validates the filter options for a lookup returning a single
+// image, which requires one of name or name_regex.
+func (f *ImageFilterOptions) Prepare() []error {
+	var errs []error
+
+	if f.Name == "" && f.NameRegex == "" {
+		errs = append(errs, errors.New("one of name or name_regex is required"))
+	}
+
+	return append(errs, f.PrepareFilters()...)
+}
+
+// PrepareFilters validates the filter options without requiring a name, for
+// lookups returning every matching image.
+func (f *ImageFilterOptions) PrepareFilters() []error {
+	var errs []error
+
+	if f.Name != "" && f.NameRegex != "" {
+		errs = append(errs, errors.New("only one of name or name_regex can be set"))
+	}
+
+	if f.NameRegex != "" {
+		if _, err := regexp.Compile(f.NameRegex); err != nil {
+			errs = append(errs, fmt.Errorf("invalid name_regex: %s", err))
+		}
+	}
+
+	if f.Type != "" {
+		if !contains(validImageTypes, f.Type) {
+			errs = append(errs, fmt.Errorf("invalid type; must be one of: %v", validImageTypes))
+		}
+	}
+
+	if len(f.Tags) > 0 && f.Type != "" && f.Type != "user" {
+		errs = append(errs, errors.New("tags can only be used with images of type user"))
+	}
+
+	if f.MinCreatedAt != "" {
+		if _, err := time.Parse(time.RFC3339, f.MinCreatedAt); err != nil {
+			errs = append(errs, fmt.Errorf("invalid min_created_at: %s", err))
+		}
+	}
+
+	if f.MaxAge < 0 {
+		errs = append(errs, errors.New("max_age must be positive"))
+	}
+
+	if f.MinDiskSize < 0 {
+		errs = append(errs, errors.New("min_disk_size must be positive"))
+	}
+
+	if f.SortBy != "" && !contains(validImageSortBy, f.SortBy) {
+		errs = append(errs, fmt.Errorf("invalid sort_by; must be one of: %v", validImageSortBy))
+	}
+
+	return errs
+}
+
+type imageListFunc func(context.Context, *godo.ListOptions) ([]godo.Image, *godo.Response, error)
+
+// ListImages returns every image of the given type available to the
+// account, following pagination. An empty type lists all images.
+func ListImages(ctx context.Context, client *godo.Client, imageType string) ([]godo.Image, error) {
+	listFunc := client.Images.List
+	switch imageType {
+	case "user":
+		listFunc = client.Images.ListUser
+	case "application":
+		listFunc = client.Images.ListApplication
+	case "distribution":
+		listFunc = client.Images.ListDistribution
+	}
+
+	return listAllImages(ctx, listFunc)
+}
+
+// ListImagesByTag returns every image with the given tag, following
+// pagination.
+func ListImagesByTag(ctx context.Context, client *godo.Client, tag string) ([]godo.Image, error) {
+	return listAllImages(ctx, func(ctx context.Context, opts *godo.ListOptions) ([]godo.Image, *godo.Response, error) {
+		return client.Images.ListByTag(ctx, tag, opts)
+	})
+}
+
+func listAllImages(ctx context.Context, listFunc imageListFunc) ([]godo.Image, error) {
+	opts := &godo.ListOptions{
+		Page:    1,
+		PerPage: 200,
+	}
+
+	var imageList []godo.Image
+	for {
+		images, resp, err := listFunc(ctx, opts)
+
+		if err != nil {
+			return nil, err
+		}
+
+		imageList = append(imageList, images...)
+
+		if resp.Links == nil || resp.Links.IsLastPage() {
+			break
+		}
+
+		page, err := resp.Links.CurrentPage()
+		if err != nil {
+			return nil, err
+		}
+
+		opts.Page = page + 1
+	}
+
+	return imageList, nil
+}
+
+// SearchImages lists the images that could match the filter. When tags are
+// set, only the images with the first tag are listed; the remaining tags
+// are checked by FilterImages.
+func SearchImages(ctx context.Context, client *godo.Client, f *ImageFilterOptions) ([]godo.Image, error) {
+	if len(f.Tags) > 0 {
+		return ListImagesByTag(ctx, client, f.Tags[0])
+	}
+
+	return ListImages(ctx, client, f.Type)
+}
+
+// FindImage lists the images matching the filter's type and returns the
+// single image selected by the filter.
+func FindImage(ctx context.Context, client *godo.Client, f *ImageFilterOptions) (godo.Image, error) {
+	images, err := SearchImages(ctx, client, f)
+	if err != nil {
+		return godo.Image{}, err
+	}
+
+	return FilterImages(f, images)
+}
+
+// FilterImages returns the single image selected by the filter options.
+func FilterImages(f *ImageFilterOptions, images []godo.Image) (godo.Image, error) {
+	result := MatchImages(f, images)
+
+	if len(result) > 1 {
+		if f.Latest {
+			return result[0], nil
+		}
+
+		return godo.Image{}, fmt.Errorf("More than one matching image found: %v", result)
+	}
+	if len(result) == 0 {
+		return godo.Image{}, errors.New("No matching image found")
+	}
+
+	return result[0], nil
+}
+
+// MatchImages returns every image matching the filter options, ordered by
+// `sort_by`. When neither name nor name_regex is set, images are not
+// filtered by name.
+func MatchImages(f *ImageFilterOptions, images []godo.Image) []godo.Image {
+	result := images
+	if f.Name != "" {
+		result = filterByName(result, f.Name)
+	}
+
+	if f.NameRegex != "" {
+		result = filterByNameRegex(result, f.NameRegex)
+	}
+
+	if f.Region != "" {
+		result = filterByRegion(result, f.Region)
+	}
+
+	result = filterByMetadata(f, result)
+	sortImages(result, f.SortBy)
+
+	return result
+}
+
+func filterByName(images []godo.Image, name string) []godo.Image {
+	result := make([]godo.Image, 0)
+	for _, i := range images {
+		if i.Name == name {
+			result = append(result, i)
+		}
+	}
+
+	return result
+}
+
+func filterByNameRegex(images []godo.Image, name string) []godo.Image {
+	r := regexp.MustCompile(name)
+	result := make([]godo.Image, 0)
+	for _, i := range images {
+		if r.MatchString(i.Name) {
+			result = append(result, i)
+		}
+	}
+
+	return result
+}
+
+func filterByRegion(images []godo.Image, region string) []godo.Image {
+	result := make([]godo.Image, 0)
+	for _, i := range images {
+		for _, r := range i.Regions {
+			if r == region {
+				result = append(result, i)
+				break
+			}
+		}
+	}
+
+	return result
+}
+
+// filterByMetadata applies the tag, distribution, status, creation time and
+// disk size filters.
+func filterByMetadata(f *ImageFilterOptions, images []godo.Image) []godo.Image {
+	var minCreated time.Time
+	if f.MinCreatedAt != "" {
+		minCreated, _ = time.Parse(time.RFC3339, f.MinCreatedAt)
+	}
+	if f.MaxAge > 0 {
+		if since := timeNow().Add(-f.MaxAge); since.After(minCreated) {
+			minCreated = since
+		}
+	}
+
+	result := make([]godo.Image, 0)
+	for _, i := range images {
+		if !containsAll(i.Tags, f.Tags) {
+			continue
+		}
+		if f.Distribution != "" && i.Distribution != f.Distribution {
+			continue
+		}
+		if f.Status != "" && i.Status != f.Status {
+			continue
+		}
+		if f.MinDiskSize > 0 && i.MinDiskSize > f.MinDiskSize {
+			continue
+		}
+		if !minCreated.IsZero() {
+			created, err := time.Parse(time.RFC3339, i.Created)
+			if err != nil || created.Before(minCreated) {
+				continue
+			}
+		}
+
+		result = append(result, i)
+	}
+
+	return result
+}
+
+// sortImages orders the images so that the one `latest` should select comes
+// first.
+func sortImages(images []godo.Image, sortBy string) {
+	sort.SliceStable(images, func(i, j int) bool {
+		switch sortBy {
+		case "name":
+			return images[i].Name > images[j].Name
+		case "size":
+			return images[i].SizeGigaBytes > images[j].SizeGigaBytes
+		default:
+			itime, _ := time.Parse(time.RFC3339, images[i].Created)
+			jtime, _ := time.Parse(time.RFC3339, images[j].Created)
+			return itime.Unix() > jtime.Unix()
+		}
+	})
+}
+
+func contains(list []string, term string) bool {
+	for _, t := range list {
+		if t == term {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAll(list []string, terms []string) bool {
+	for _, t := range terms {
+		if !contains(list, t) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,53 @@
+// Code generated by "packer-sdc mapstructure-to-hcl2"; DO NOT EDIT.
+
+package common
+
+import (
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// FlatImageFilterOptions is an auto-generated flat version of ImageFilterOptions.
+// Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
+type FlatImageFilterOptions struct {
+	Name         *string  `mapstructure:"name" cty:"name" hcl:"name"`
+	NameRegex    *string  `mapstructure:"name_regex" cty:"name_regex" hcl:"name_regex"`
+	Type         *string  `mapstructure:"type" cty:"type" hcl:"type"`
+	Region       *string  `mapstructure:"region" cty:"region" hcl:"region"`
+	Latest       *bool    `mapstructure:"latest" cty:"latest" hcl:"latest"`
+	Tags         []string `mapstructure:"tags" cty:"tags" hcl:"tags"`
+	Distribution *string  `mapstructure:"distribution" cty:"distribution" hcl:"distribution"`
+	Status       *string  `mapstructure:"status" cty:"status" hcl:"status"`
+	MinCreatedAt *string  `mapstructure:"min_created_at" cty:"min_created_at" hcl:"min_created_at"`
+	MaxAge       *string  `mapstructure:"max_age" cty:"max_age" hcl:"max_age"`
+	MinDiskSize  *int     `mapstructure:"min_disk_size" cty:"min_disk_size" hcl:"min_disk_size"`
+	SortBy       *string  `mapstructure:"sort_by" cty:"sort_by" hcl:"sort_by"`
+}
+
+// FlatMapstructure returns a new FlatImageFilterOptions.
+// FlatImageFilterOptions is an auto-generated flat version of ImageFilterOptions.
+// Where the contents a fields with a `mapstructure:,squash` tag are bubbled up.
+func (*ImageFilterOptions) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec } {
+	return new(FlatImageFilterOptions)
+}
+
+// HCL2Spec returns the hcl spec of a ImageFilterOptions.
+// This spec is used by HCL to read the fields of ImageFilterOptions.
+// The decoded values from this spec will then be applied to a FlatImageFilterOptions.
+func (*FlatImageFilterOptions) HCL2Spec() map[string]hcldec.Spec {
+	s := map[string]hcldec.Spec{
+		"name":           &hcldec.AttrSpec{Name: "name", Type: cty.String, Required: false},
+		"name_regex":     &hcldec.AttrSpec{Name: "name_regex", Type: cty.String, Required: false},
+		"type":           &hcldec.AttrSpec{Name: "type", Type: cty.String, Required: false},
+		"region":         &hcldec.AttrSpec{Name: "region", Type: cty.String, Required: false},
+		"latest":         &hcldec.AttrSpec{Name: "latest", Type: cty.Bool, Required: false},
+		"tags":           &hcldec.AttrSpec{Name: "tags", Type: cty.List(cty.String), Required: false},
+		"distribution":   &hcldec.AttrSpec{Name: "distribution", Type: cty.String, Required: false},
+		"status":         &hcldec.AttrSpec{Name: "status", Type: cty.String, Required: false},
+		"min_created_at": &hcldec.AttrSpec{Name: "min_created_at", Type: cty.String, Required: false},
+		"max_age":        &hcldec.AttrSpec{Name: "max_age", Type: cty.String, Required: false},
+		"min_disk_size":  &hcldec.AttrSpec{Name: "min_disk_size", Type: cty.Number, Required: false},
+		"sort_by":        &hcldec.AttrSpec{Name: "sort_by", Type: cty.String, Required: false},
+	}
+	return s
+}
@@ -0,0 +1,256 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/digitalocean/godo"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilterImages(t *testing.T) {
+	tests := []struct {
+		name          string
+		config        *ImageFilterOptions
+		images        []godo.Image
+		expectedImage godo.Image
+		expectedError string
+	}{
+		{
+			name:   "by name - single match",
+			config: &ImageFilterOptions{Name: "test-image"},
+			images: []godo.Image{
+				{ID: 1, Name: "test-image"},
+				{ID: 2, Name: "test-image-01"},
+			},
+			expectedImage: godo.Image{ID: 1, Name: "test-image"},
+		},
+		{
+			name:   "by name - multiple matches",
+			config: &ImageFilterOptions{Name: "test-image"},
+			images: []godo.Image{
+				{ID: 1, Name: "test-image"},
+				{ID: 2, Name: "test-image"},
+			},
+			expectedError: "More than one matching image found:",
+		},
+		{
+			name:   "by name - multiple matches - latest",
+			config: &ImageFilterOptions{Name: "test-image", Latest: true},
+			images: []godo.Image{
+				{ID: 1, Name: "test-image", Created: "2022-08-08T21:31:54Z"},
+				{ID: 2, Name: "test-image", Created: "2022-08-10T21:31:54Z"},
+			},
+			expectedImage: godo.Image{ID: 2, Name: "test-image", Created: "2022-08-10T21:31:54Z"},
+		},
+		{
+			name:   "by name - multiple matches - region filter",
+			config: &ImageFilterOptions{Name: "test-image", Region: "nyc3", Latest: true},
+			images: []godo.Image{
+				{ID: 1, Name: "test-image", Created: "2022-08-08T21:31:54Z", Regions: []string{"nyc3"}},
+				{ID: 2, Name: "test-image", Created: "2022-08-10T21:31:54Z", Regions: []string{"nyc2"}},
+			},
+			expectedImage: godo.Image{ID: 1, Name: "test-image", Created: "2022-08-08T21:31:54Z", Regions: []string{"nyc3"}},
+		},
+		{
+			name:   "by name - no matches",
+			config: &ImageFilterOptions{Name: "test-image"},
+			images: []godo.Image{
+				{ID: 1, Name: "test-image-01", Created: "2022-08-08T21:31:54Z", Regions: []string{"nyc3"}},
+				{ID: 2, Name: "test-image-02", Created: "2022-08-10T21:31:54Z", Regions: []string{"nyc2"}},
+			},
+			expectedError: "No matching image found",
+		},
+
+		{
+			name:   "regex - single match",
+			config: &ImageFilterOptions{NameRegex: "test-image-.*"},
+			images: []godo.Image{
+				{ID: 1, Name: "test-image"},
+				{ID: 2, Name: "test-image-01"},
+			},
+			expectedImage: godo.Image{ID: 2, Name: "test-image-01"},
+		},
+		{
+			name:   "regex - multiple matches",
+			config: &ImageFilterOptions{NameRegex: "test-image-.*"},
+			images: []godo.Image{
+				{ID: 1, Name: "test-image-01"},
+				{ID: 2, Name: "test-image-02"},
+			},
+			expectedError: "More than one matching image found:",
+		},
+		{
+			name:   "regex - multiple matches - latest",
+			config: &ImageFilterOptions{NameRegex: "test-image-.*", Latest: true},
+			images: []godo.Image{
+				{ID: 1, Name: "test-image-01", Created: "2022-08-08T21:31:54Z"},
+				{ID: 2, Name: "test-image-02", Created: "2022-08-10T21:31:54Z"},
+			},
+			expectedImage: godo.Image{ID: 2, Name: "test-image-02", Created: "2022-08-10T21:31:54Z"},
+		},
+		{
+			name:   "regex - multiple matches - region filter",
+			config: &ImageFilterOptions{NameRegex: "test-image-.*", Region: "nyc3", Latest: true},
+			images: []godo.Image{
+				{ID: 1, Name: "test-image-01", Created: "2022-08-08T21:31:54Z", Regions: []string{"nyc3"}},
+				{ID: 2, Name: "test-image-02", Created: "2022-08-10T21:31:54Z", Regions: []string{"nyc2"}},
+			},
+			expectedImage: godo.Image{ID: 1, Name: "test-image-01", Created: "2022-08-08T21:31:54Z", Regions: []string{"nyc3"}},
+		},
+		{
+			name:   "regex - no matches",
+			config: &ImageFilterOptions{NameRegex: "test-image-.*"},
+			images: []godo.Image{
+				{ID: 1, Name: "test-image01", Created: "2022-08-08T21:31:54Z", Regions: []string{"nyc3"}},
+				{ID: 2, Name: "test-image02", Created: "2022-08-10T21:31:54Z", Regions: []string{"nyc2"}},
+			},
+			expectedError: "No matching image found",
+		},
+
+		{
+			name:   "tags - all must match",
+			config: &ImageFilterOptions{NameRegex: "test-image-.*", Tags: []string{"team:payments", "channel:stable"}},
+			images: []godo.Image{
+				{ID: 1, Name: "test-image-01", Tags: []string{"team:payments"}},
+				{ID: 2, Name: "test-image-02", Tags: []string{"channel:stable", "team:payments"}},
+			},
+			expectedImage: godo.Image{ID: 2, Name: "test-image-02", Tags: []string{"channel:stable", "team:payments"}},
+		},
+		{
+			name:   "distribution and status",
+			config: &ImageFilterOptions{NameRegex: "test-image-.*", Distribution: "Ubuntu", Status: "available"},
+			images: []godo.Image{
+				{ID: 1, Name: "test-image-01", Distribution: "Debian", Status: "available"},
+				{ID: 2, Name: "test-image-02", Distribution: "Ubuntu", Status: "pending"},
+				{ID: 3, Name: "test-image-03", Distribution: "Ubuntu", Status: "available"},
+			},
+			expectedImage: godo.Image{ID: 3, Name: "test-image-03", Distribution: "Ubuntu", Status: "available"},
+		},
+		{
+			name:   "min disk size",
+			config: &ImageFilterOptions{NameRegex: "test-image-.*", MinDiskSize: 25},
+			images: []godo.Image{
+				{ID: 1, Name: "test-image-01", MinDiskSize: 50},
+				{ID: 2, Name: "test-image-02", MinDiskSize: 25},
+			},
+			expectedImage: godo.Image{ID: 2, Name: "test-image-02", MinDiskSize: 25},
+		},
+		{
+			name:   "min created at",
+			config: &ImageFilterOptions{NameRegex: "test-image-.*", MinCreatedAt: "2022-08-09T00:00:00Z"},
+			images: []godo.Image{
+				{ID: 1, Name: "test-image-01", Created: "2022-08-08T21:31:54Z"},
+				{ID: 2, Name: "test-image-02", Created: "2022-08-10T21:31:54Z"},
+			},
+			expectedImage: godo.Image{ID: 2, Name: "test-image-02", Created: "2022-08-10T21:31:54Z"},
+		},
+		{
+			name:   "max age",
+			config: &ImageFilterOptions{NameRegex: "test-image-.*", MaxAge: 48 * time.Hour},
+			images: []godo.Image{
+				{ID: 1, Name: "test-image-01", Created: "2022-08-08T21:31:54Z"},
+				{ID: 2, Name: "test-image-02", Created: "2022-08-10T21:31:54Z"},
+			},
+			expectedImage: godo.Image{ID: 2, Name: "test-image-02", Created: "2022-08-10T21:31:54Z"},
+		},
+		{
+			name:   "latest - sort by name",
+			config: &ImageFilterOptions{NameRegex: "test-image-.*", Latest: true, SortBy: "name"},
+			images: []godo.Image{
+				{ID: 1, Name: "test-image-02", Created: "2022-08-08T21:31:54Z"},
+				{ID: 2, Name: "test-image-01", Created: "2022-08-10T21:31:54Z"},
+			},
+			expectedImage: godo.Image{ID: 1, Name: "test-image-02", Created: "2022-08-08T21:31:54Z"},
+		},
+		{
+			name:   "latest - sort by size",
+			config: &ImageFilterOptions{NameRegex: "test-image-.*", Latest: true, SortBy: "size"},
+			images: []godo.Image{
+				{ID: 1, Name: "test-image-01", SizeGigaBytes: 2.5},
+				{ID: 2, Name: "test-image-02", SizeGigaBytes: 1.5},
+			},
+			expectedImage: godo.Image{ID: 1, Name: "test-image-01", SizeGigaBytes: 2.5},
+		},
+	}
+
+	timeNow = func() time.Time { return time.Date(2022, 8, 11, 0, 0, 0, 0, time.UTC) }
+	defer func() { timeNow = time.Now }()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := FilterImages(tt.config, tt.images)
+			if tt.expectedError == "" {
+				require.NoError(t, err)
+				require.Equal(t, tt.expectedImage, out)
+			} else {
+				require.Contains(t, err.Error(), tt.expectedError)
+			}
+		})
+	}
+}
+
+func TestImageFilterOptions_Prepare(t *testing.T) {
+	tests := []struct {
+		name          string
+		config        *ImageFilterOptions
+		expectedError string
+	}{
+		{
+			name:   "valid",
+			config: &ImageFilterOptions{Name: "test-image", Tags: []string{"a"}, SortBy: "size", MinCreatedAt: "2022-08-08T21:31:54Z"},
+		},
+		{
+			name:          "tags with non-user type",
+			config:        &ImageFilterOptions{Name: "test-image", Tags: []string{"a"}, Type: "distribution"},
+			expectedError: "tags can only be used with images of type user",
+		},
+		{
+			name:          "invalid min_created_at",
+			config:        &ImageFilterOptions{Name: "test-image", MinCreatedAt: "yesterday"},
+			expectedError: "invalid min_created_at",
+		},
+		{
+			name:          "invalid sort_by",
+			config:        &ImageFilterOptions{Name: "test-image", SortBy: "id"},
+			expectedError: "invalid sort_by",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := tt.config.Prepare()
+			if tt.expectedError == "" {
+				require.Empty(t, errs)
+			} else {
+				require.Len(t, errs, 1)
+				require.Contains(t, errs[0].Error(), tt.expectedError)
+			}
+		})
+	}
+}
+
+func TestSearchImages_Tags(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/v2/images", r.URL.Path)
+		require.Equal(t, "team:payments", r.URL.Query().Get("tag_name"))
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"images": []godo.Image{{ID: 1, Name: "test-image", Tags: []string{"team:payments"}}},
+		})
+	}))
+	defer server.Close()
+
+	client := godo.NewClient(nil)
+	client.BaseURL, _ = url.Parse(server.URL)
+
+	images, err := SearchImages(context.Background(), client,
+		&ImageFilterOptions{Name: "test-image", Tags: []string{"team:payments", "channel:stable"}})
+	require.NoError(t, err)
+	require.Len(t, images, 1)
+	require.Equal(t, 1, images[0].ID)
+}
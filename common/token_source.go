@@ -1,4 +1,4 @@
-package digitalocean
+package common
 
 import (
 	"golang.org/x/oauth2"
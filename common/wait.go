@@ -0,0 +1,127 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"time"
+)
+
+const (
+	// DefaultPollInterval is the delay before the first retry of a waiter
+	// when no interval is configured.
+	DefaultPollInterval = 3 * time.Second
+	// DefaultMaxPollInterval caps the exponential backoff of a waiter when
+	// no maximum is configured.
+	DefaultMaxPollInterval = 30 * time.Second
+)
+
+// WaitOptions configures a call to WaitFor.
+type WaitOptions struct {
+	// Description of what is being waited on, such as
+	// "droplet to become active". Used in logs and errors.
+	Description string
+	// The delay before the first retry. Each following delay is doubled
+	// until MaxPollInterval is reached, with a little jitter added.
+	PollInterval time.Duration
+	// The upper bound for the delay between two checks.
+	MaxPollInterval time.Duration
+	// How long to wait in total. Zero means wait until the context is done.
+	Timeout time.Duration
+}
+
+// TimeoutError is returned by WaitFor when the timeout elapsed before the
+// condition was met.
+type TimeoutError struct {
+	Description string
+	Timeout     time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("Timeout after %s while waiting for %s", e.Timeout, e.Description)
+}
+
+// CanceledError is returned by WaitFor when the context passed in was
+// canceled before the condition was met.
+type CanceledError struct {
+	Description string
+	Err         error
+}
+
+func (e *CanceledError) Error() string {
+	return fmt.Sprintf("Canceled while waiting for %s: %s", e.Description, e.Err)
+}
+
+func (e *CanceledError) Unwrap() error {
+	return e.Err
+}
+
+// WaitFor calls check until it reports true, returns an error, the timeout
+// elapses, or ctx is done. The delay between calls grows exponentially from
+// PollInterval up to MaxPollInterval.
+func WaitFor(ctx context.Context, opts WaitOptions, check func(context.Context) (bool, error)) error {
+	interval := opts.PollInterval
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	maxInterval := opts.MaxPollInterval
+	if maxInterval <= 0 {
+		maxInterval = DefaultMaxPollInterval
+	}
+	if maxInterval < interval {
+		maxInterval = interval
+	}
+
+	waitCtx := ctx
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		waitCtx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+		log.Printf("Waiting for up to %d seconds for %s", opts.Timeout/time.Second, opts.Description)
+	}
+
+	for attempts := 1; ; attempts++ {
+		log.Printf("[DEBUG] Checking %s... (attempt: %d)", opts.Description, attempts)
+		done, err := check(waitCtx)
+		if waitCtx.Err() != nil {
+			return waitError(ctx, opts)
+		}
+		if err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+
+		timer := time.NewTimer(withJitter(interval))
+		select {
+		case <-waitCtx.Done():
+			timer.Stop()
+			return waitError(ctx, opts)
+		case <-timer.C:
+		}
+
+		interval *= 2
+		if interval > maxInterval {
+			interval = maxInterval
+		}
+	}
+}
+
+// waitError tells apart a canceled parent context from an elapsed timeout.
+func waitError(parent context.Context, opts WaitOptions) error {
+	if err := parent.Err(); err != nil {
+		return &CanceledError{Description: opts.Description, Err: err}
+	}
+	return &TimeoutError{Description: opts.Description, Timeout: opts.Timeout}
+}
+
+// withJitter adds up to 20% to d so that concurrent waiters spread out
+// their requests.
+func withJitter(d time.Duration) time.Duration {
+	if j := int64(d) / 5; j > 0 {
+		return d + time.Duration(rand.Int63n(j))
+	}
+	return d
+}
@@ -0,0 +1,66 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWaitFor(t *testing.T) {
+	opts := WaitOptions{
+		Description:     "test condition",
+		PollInterval:    time.Millisecond,
+		MaxPollInterval: 5 * time.Millisecond,
+		Timeout:         time.Second,
+	}
+
+	t.Run("succeeds", func(t *testing.T) {
+		attempts := 0
+		err := WaitFor(context.Background(), opts, func(context.Context) (bool, error) {
+			attempts++
+			return attempts == 3, nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, 3, attempts)
+	})
+
+	t.Run("returns check errors", func(t *testing.T) {
+		expected := errors.New("boom")
+		err := WaitFor(context.Background(), opts, func(context.Context) (bool, error) {
+			return false, expected
+		})
+		require.ErrorIs(t, err, expected)
+	})
+
+	t.Run("times out", func(t *testing.T) {
+		opts := opts
+		opts.Timeout = 20 * time.Millisecond
+		err := WaitFor(context.Background(), opts, func(context.Context) (bool, error) {
+			return false, nil
+		})
+
+		var timeoutErr *TimeoutError
+		require.ErrorAs(t, err, &timeoutErr)
+		require.Equal(t, opts.Timeout, timeoutErr.Timeout)
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		attempts := 0
+		err := WaitFor(ctx, opts, func(context.Context) (bool, error) {
+			attempts++
+			if attempts == 2 {
+				cancel()
+			}
+			return false, nil
+		})
+
+		var canceledErr *CanceledError
+		require.ErrorAs(t, err, &canceledErr)
+		require.ErrorIs(t, err, context.Canceled)
+		require.Equal(t, 2, attempts)
+	})
+}
@@ -11,7 +11,7 @@ import (
 // Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
 type FlatConfig struct {
 	APIToken         *string  `mapstructure:"api_token" required:"true" cty:"api_token" hcl:"api_token"`
-	APIURL           *string  `mapstructure:"api_url" cty:"api_url" hcl:"api_url"`
+	APIURL           *string  `mapstructure:"api_url" required:"false" cty:"api_url" hcl:"api_url"`
 	HTTPRetryMax     *int     `mapstructure:"http_retry_max" required:"false" cty:"http_retry_max" hcl:"http_retry_max"`
 	HTTPRetryWaitMax *float64 `mapstructure:"http_retry_wait_max" required:"false" cty:"http_retry_wait_max" hcl:"http_retry_wait_max"`
 	HTTPRetryWaitMin *float64 `mapstructure:"http_retry_wait_min" required:"false" cty:"http_retry_wait_min" hcl:"http_retry_wait_min"`
@@ -20,6 +20,13 @@ type FlatConfig struct {
 	Type             *string  `mapstructure:"type" cty:"type" hcl:"type"`
 	Region           *string  `mapstructure:"region" cty:"region" hcl:"region"`
 	Latest           *bool    `mapstructure:"latest" cty:"latest" hcl:"latest"`
+	Tags             []string `mapstructure:"tags" cty:"tags" hcl:"tags"`
+	Distribution     *string  `mapstructure:"distribution" cty:"distribution" hcl:"distribution"`
+	Status           *string  `mapstructure:"status" cty:"status" hcl:"status"`
+	MinCreatedAt     *string  `mapstructure:"min_created_at" cty:"min_created_at" hcl:"min_created_at"`
+	MaxAge           *string  `mapstructure:"max_age" cty:"max_age" hcl:"max_age"`
+	MinDiskSize      *int     `mapstructure:"min_disk_size" cty:"min_disk_size" hcl:"min_disk_size"`
+	SortBy           *string  `mapstructure:"sort_by" cty:"sort_by" hcl:"sort_by"`
 }
 
 // FlatMapstructure returns a new FlatConfig.
@@ -44,6 +51,13 @@ func (*FlatConfig) HCL2Spec() map[string]hcldec.Spec {
 		"type":                &hcldec.AttrSpec{Name: "type", Type: cty.String, Required: false},
 		"region":              &hcldec.AttrSpec{Name: "region", Type: cty.String, Required: false},
 		"latest":              &hcldec.AttrSpec{Name: "latest", Type: cty.Bool, Required: false},
+		"tags":                &hcldec.AttrSpec{Name: "tags", Type: cty.List(cty.String), Required: false},
+		"distribution":        &hcldec.AttrSpec{Name: "distribution", Type: cty.String, Required: false},
+		"status":              &hcldec.AttrSpec{Name: "status", Type: cty.String, Required: false},
+		"min_created_at":      &hcldec.AttrSpec{Name: "min_created_at", Type: cty.String, Required: false},
+		"max_age":             &hcldec.AttrSpec{Name: "max_age", Type: cty.String, Required: false},
+		"min_disk_size":       &hcldec.AttrSpec{Name: "min_disk_size", Type: cty.Number, Required: false},
+		"sort_by":             &hcldec.AttrSpec{Name: "sort_by", Type: cty.String, Required: false},
 	}
 	return s
 }
@@ -51,8 +65,19 @@ func (*FlatConfig) HCL2Spec() map[string]hcldec.Spec {
 // FlatDatasourceOutput is an auto-generated flat version of DatasourceOutput.
 // Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
 type FlatDatasourceOutput struct {
-	ImageID      *int     `mapstructure:"image_id" cty:"image_id" hcl:"image_id"`
-	ImageRegions []string `mapstructure:"image_regions" cty:"image_regions" hcl:"image_regions"`
+	ImageID       *int     `mapstructure:"image_id" cty:"image_id" hcl:"image_id"`
+	ImageRegions  []string `mapstructure:"image_regions" cty:"image_regions" hcl:"image_regions"`
+	Name          *string  `mapstructure:"name" cty:"name" hcl:"name"`
+	Slug          *string  `mapstructure:"slug" cty:"slug" hcl:"slug"`
+	Distribution  *string  `mapstructure:"distribution" cty:"distribution" hcl:"distribution"`
+	Description   *string  `mapstructure:"description" cty:"description" hcl:"description"`
+	Type          *string  `mapstructure:"type" cty:"type" hcl:"type"`
+	MinDiskSize   *int     `mapstructure:"min_disk_size" cty:"min_disk_size" hcl:"min_disk_size"`
+	SizeGigaBytes *float64 `mapstructure:"size_gigabytes" cty:"size_gigabytes" hcl:"size_gigabytes"`
+	Tags          []string `mapstructure:"tags" cty:"tags" hcl:"tags"`
+	CreatedAt     *string  `mapstructure:"created_at" cty:"created_at" hcl:"created_at"`
+	Status        *string  `mapstructure:"status" cty:"status" hcl:"status"`
+	Public        *bool    `mapstructure:"public" cty:"public" hcl:"public"`
 }
 
 // FlatMapstructure returns a new FlatDatasourceOutput.
@@ -67,8 +92,19 @@ func (*DatasourceOutput) FlatMapstructure() interface{ HCL2Spec() map[string]hcl
 // The decoded values from this spec will then be applied to a FlatDatasourceOutput.
 func (*FlatDatasourceOutput) HCL2Spec() map[string]hcldec.Spec {
 	s := map[string]hcldec.Spec{
-		"image_id":      &hcldec.AttrSpec{Name: "image_id", Type: cty.Number, Required: false},
-		"image_regions": &hcldec.AttrSpec{Name: "image_regions", Type: cty.List(cty.String), Required: false},
+		"image_id":       &hcldec.AttrSpec{Name: "image_id", Type: cty.Number, Required: false},
+		"image_regions":  &hcldec.AttrSpec{Name: "image_regions", Type: cty.List(cty.String), Required: false},
+		"name":           &hcldec.AttrSpec{Name: "name", Type: cty.String, Required: false},
+		"slug":           &hcldec.AttrSpec{Name: "slug", Type: cty.String, Required: false},
+		"distribution":   &hcldec.AttrSpec{Name: "distribution", Type: cty.String, Required: false},
+		"description":    &hcldec.AttrSpec{Name: "description", Type: cty.String, Required: false},
+		"type":           &hcldec.AttrSpec{Name: "type", Type: cty.String, Required: false},
+		"min_disk_size":  &hcldec.AttrSpec{Name: "min_disk_size", Type: cty.Number, Required: false},
+		"size_gigabytes": &hcldec.AttrSpec{Name: "size_gigabytes", Type: cty.Number, Required: false},
+		"tags":           &hcldec.AttrSpec{Name: "tags", Type: cty.List(cty.String), Required: false},
+		"created_at":     &hcldec.AttrSpec{Name: "created_at", Type: cty.String, Required: false},
+		"status":         &hcldec.AttrSpec{Name: "status", Type: cty.String, Required: false},
+		"public":         &hcldec.AttrSpec{Name: "public", Type: cty.Bool, Required: false},
 	}
 	return s
 }
@@ -11,7 +11,7 @@ import (
 	"testing"
 
 	"github.com/digitalocean/godo"
-	builder "github.com/digitalocean/packer-plugin-digitalocean/builder/digitalocean"
+	docommon "github.com/digitalocean/packer-plugin-digitalocean/common"
 	"github.com/hashicorp/packer-plugin-sdk/acctest"
 	"golang.org/x/oauth2"
 )
@@ -135,7 +135,7 @@ func TestAccDatasource_Basic(t *testing.T) {
 	if token == "" {
 		t.Fatal("DIGITALOCEAN_TOKEN environment variable required")
 	}
-	oauthClient := oauth2.NewClient(context.TODO(), &builder.APITokenSource{
+	oauthClient := oauth2.NewClient(context.TODO(), &docommon.APITokenSource{
 		AccessToken: token,
 	})
 	client, err := godo.New(oauthClient, godo.WithRetryAndBackoffs(godo.RetryConfig{
@@ -4,119 +4,42 @@ import (
 	"testing"
 
 	"github.com/digitalocean/godo"
+	"github.com/hashicorp/packer-plugin-sdk/hcl2helper"
 	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
 )
 
-func TestFilterImages(t *testing.T) {
-	tests := []struct {
-		name          string
-		config        *Config
-		images        []godo.Image
-		expectedImage godo.Image
-		expectedError string
-	}{
-		{
-			name:   "by name - single match",
-			config: &Config{Name: "test-image"},
-			images: []godo.Image{
-				{ID: 1, Name: "test-image"},
-				{ID: 2, Name: "test-image-01"},
-			},
-			expectedImage: godo.Image{ID: 1, Name: "test-image"},
-		},
-		{
-			name:   "by name - multiple matches",
-			config: &Config{Name: "test-image"},
-			images: []godo.Image{
-				{ID: 1, Name: "test-image"},
-				{ID: 2, Name: "test-image"},
-			},
-			expectedError: "More than one matching image found:",
-		},
-		{
-			name:   "by name - multiple matches - latest",
-			config: &Config{Name: "test-image", Latest: true},
-			images: []godo.Image{
-				{ID: 1, Name: "test-image", Created: "2022-08-08T21:31:54Z"},
-				{ID: 2, Name: "test-image", Created: "2022-08-10T21:31:54Z"},
-			},
-			expectedImage: godo.Image{ID: 2, Name: "test-image", Created: "2022-08-10T21:31:54Z"},
-		},
-		{
-			name:   "by name - multiple matches - region filter",
-			config: &Config{Name: "test-image", Region: "nyc3", Latest: true},
-			images: []godo.Image{
-				{ID: 1, Name: "test-image", Created: "2022-08-08T21:31:54Z", Regions: []string{"nyc3"}},
-				{ID: 2, Name: "test-image", Created: "2022-08-10T21:31:54Z", Regions: []string{"nyc2"}},
-			},
-			expectedImage: godo.Image{ID: 1, Name: "test-image", Created: "2022-08-08T21:31:54Z", Regions: []string{"nyc3"}},
-		},
-		{
-			name:   "by name - no matches",
-			config: &Config{Name: "test-image"},
-			images: []godo.Image{
-				{ID: 1, Name: "test-image-01", Created: "2022-08-08T21:31:54Z", Regions: []string{"nyc3"}},
-				{ID: 2, Name: "test-image-02", Created: "2022-08-10T21:31:54Z", Regions: []string{"nyc2"}},
-			},
-			expectedError: "No matching image found",
-		},
-
-		{
-			name:   "regex - single match",
-			config: &Config{NameRegex: "test-image-.*"},
-			images: []godo.Image{
-				{ID: 1, Name: "test-image"},
-				{ID: 2, Name: "test-image-01"},
-			},
-			expectedImage: godo.Image{ID: 2, Name: "test-image-01"},
-		},
-		{
-			name:   "regex - multiple matches",
-			config: &Config{NameRegex: "test-image-.*"},
-			images: []godo.Image{
-				{ID: 1, Name: "test-image-01"},
-				{ID: 2, Name: "test-image-02"},
-			},
-			expectedError: "More than one matching image found:",
-		},
-		{
-			name:   "regex - multiple matches - latest",
-			config: &Config{NameRegex: "test-image-.*", Latest: true},
-			images: []godo.Image{
-				{ID: 1, Name: "test-image-01", Created: "2022-08-08T21:31:54Z"},
-				{ID: 2, Name: "test-image-02", Created: "2022-08-10T21:31:54Z"},
-			},
-			expectedImage: godo.Image{ID: 2, Name: "test-image-02", Created: "2022-08-10T21:31:54Z"},
-		},
-		{
-			name:   "regex - multiple matches - region filter",
-			config: &Config{NameRegex: "test-image-.*", Region: "nyc3", Latest: true},
-			images: []godo.Image{
-				{ID: 1, Name: "test-image-01", Created: "2022-08-08T21:31:54Z", Regions: []string{"nyc3"}},
-				{ID: 2, Name: "test-image-02", Created: "2022-08-10T21:31:54Z", Regions: []string{"nyc2"}},
-			},
-			expectedImage: godo.Image{ID: 1, Name: "test-image-01", Created: "2022-08-08T21:31:54Z", Regions: []string{"nyc3"}},
-		},
-		{
-			name:   "regex - no matches",
-			config: &Config{NameRegex: "test-image-.*"},
-			images: []godo.Image{
-				{ID: 1, Name: "test-image01", Created: "2022-08-08T21:31:54Z", Regions: []string{"nyc3"}},
-				{ID: 2, Name: "test-image02", Created: "2022-08-10T21:31:54Z", Regions: []string{"nyc2"}},
-			},
-			expectedError: "No matching image found",
-		},
+func TestDatasourceOutput(t *testing.T) {
+	image := godo.Image{
+		ID:            42,
+		Name:          "golden-image-1",
+		Slug:          "",
+		Distribution:  "Ubuntu",
+		Description:   "A golden image",
+		Type:          "snapshot",
+		MinDiskSize:   25,
+		SizeGigaBytes: 2.34,
+		Regions:       []string{"nyc3", "sfo3"},
+		Tags:          []string{"golden"},
+		Created:       "2022-01-02T15:04:05Z",
+		Status:        "available",
+		Public:        false,
 	}
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			out, err := filterImages(tt.config, tt.images)
-			if tt.expectedError == "" {
-				require.NoError(t, err)
-				require.Equal(t, tt.expectedImage, out)
-			} else {
-				require.Contains(t, err.Error(), tt.expectedError)
-			}
-		})
-	}
+	output := newDatasourceOutput(image)
+	require.Equal(t, 42, output.ImageID)
+	require.Equal(t, []string{"nyc3", "sfo3"}, output.ImageRegions)
+	require.Equal(t, "golden-image-1", output.Name)
+	require.Equal(t, "Ubuntu", output.Distribution)
+	require.Equal(t, 25, output.MinDiskSize)
+	require.Equal(t, 2.34, output.SizeGigaBytes)
+	require.Equal(t, []string{"golden"}, output.Tags)
+	require.Equal(t, "2022-01-02T15:04:05Z", output.CreatedAt)
+	require.Equal(t, "available", output.Status)
+
+	d := &Datasource{}
+	val := hcl2helper.HCL2ValueFromConfig(output, d.OutputSpec())
+	require.Equal(t, cty.StringVal("golden-image-1"), val.GetAttr("name"))
+	require.Equal(t, cty.NumberIntVal(25), val.GetAttr("min_disk_size"))
+	require.Equal(t, cty.False, val.GetAttr("public"))
 }
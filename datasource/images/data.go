@@ -0,0 +1,127 @@
+//go:generate packer-sdc mapstructure-to-hcl2 -type Config,DatasourceOutput,Image
+//go:generate packer-sdc struct-markdown
+package images
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/digitalocean/godo"
+	docommon "github.com/digitalocean/packer-plugin-digitalocean/common"
+
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/packer-plugin-sdk/hcl2helper"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+	"github.com/zclconf/go-cty/cty"
+)
+
+type Config struct {
+	docommon.AccessConfig       `mapstructure:",squash"`
+	docommon.ImageFilterOptions `mapstructure:",squash"`
+	// The maximum number of images to return, after ordering by `sort_by`.
+	// By default, all matching images are returned.
+	Limit int `mapstructure:"limit"`
+}
+
+type Datasource struct {
+	config Config
+}
+
+type DatasourceOutput struct {
+	// The images matching the filters, ordered by `sort_by`.
+	Images []Image `mapstructure:"images"`
+}
+
+type Image struct {
+	// The ID of the image.
+	ID int `mapstructure:"id"`
+	// The name of the image.
+	Name string `mapstructure:"name"`
+	// The regions the image is available in.
+	Regions []string `mapstructure:"regions"`
+	// The RFC 3339 timestamp of when the image was created.
+	Created string `mapstructure:"created"`
+	// The tags applied to the image.
+	Tags []string `mapstructure:"tags"`
+}
+
+func (d *Datasource) ConfigSpec() hcldec.ObjectSpec {
+	return d.config.FlatMapstructure().HCL2Spec()
+}
+
+func (d *Datasource) Configure(raws ...interface{}) error {
+	err := config.Decode(&d.config, nil, raws...)
+	if err != nil {
+		return err
+	}
+
+	var errs *packersdk.MultiError
+
+	warns, es := d.config.AccessConfig.Prepare()
+	for _, w := range warns {
+		log.Printf("[WARN] %s", w)
+	}
+	if len(es) > 0 {
+		errs = packersdk.MultiErrorAppend(errs, es...)
+	}
+
+	if es := d.config.ImageFilterOptions.PrepareFilters(); len(es) > 0 {
+		errs = packersdk.MultiErrorAppend(errs, es...)
+	}
+
+	if d.config.Limit < 0 {
+		errs = packersdk.MultiErrorAppend(errs, errors.New("limit must be positive"))
+	}
+
+	if errs != nil && len(errs.Errors) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func (d *Datasource) OutputSpec() hcldec.ObjectSpec {
+	return (&DatasourceOutput{}).FlatMapstructure().HCL2Spec()
+}
+
+func (d *Datasource) Execute() (cty.Value, error) {
+	client, err := d.config.Client()
+	if err != nil {
+		return cty.NullVal(cty.EmptyObject), err
+	}
+
+	images, err := docommon.SearchImages(context.Background(), client, &d.config.ImageFilterOptions)
+	if err != nil {
+		return cty.NullVal(cty.EmptyObject), err
+	}
+
+	output := newDatasourceOutput(&d.config, images)
+
+	log.Printf("[DEBUG] found %d images", len(output.Images))
+
+	return hcl2helper.HCL2ValueFromConfig(output, d.OutputSpec()), nil
+}
+
+func newDatasourceOutput(c *Config, images []godo.Image) DatasourceOutput {
+	matches := docommon.MatchImages(&c.ImageFilterOptions, images)
+	if c.Limit > 0 && len(matches) > c.Limit {
+		matches = matches[:c.Limit]
+	}
+
+	output := DatasourceOutput{
+		Images: make([]Image, 0, len(matches)),
+	}
+	for _, i := range matches {
+		output.Images = append(output.Images, Image{
+			ID:      i.ID,
+			Name:    i.Name,
+			Regions: i.Regions,
+			Created: i.Created,
+			Tags:    i.Tags,
+		})
+	}
+
+	return output
+}
@@ -0,0 +1,119 @@
+// Code generated by "packer-sdc mapstructure-to-hcl2"; DO NOT EDIT.
+
+package images
+
+import (
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// FlatConfig is an auto-generated flat version of Config.
+// Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
+type FlatConfig struct {
+	APIToken         *string  `mapstructure:"api_token" required:"true" cty:"api_token" hcl:"api_token"`
+	APIURL           *string  `mapstructure:"api_url" required:"false" cty:"api_url" hcl:"api_url"`
+	HTTPRetryMax     *int     `mapstructure:"http_retry_max" required:"false" cty:"http_retry_max" hcl:"http_retry_max"`
+	HTTPRetryWaitMax *float64 `mapstructure:"http_retry_wait_max" required:"false" cty:"http_retry_wait_max" hcl:"http_retry_wait_max"`
+	HTTPRetryWaitMin *float64 `mapstructure:"http_retry_wait_min" required:"false" cty:"http_retry_wait_min" hcl:"http_retry_wait_min"`
+	Name             *string  `mapstructure:"name" cty:"name" hcl:"name"`
+	NameRegex        *string  `mapstructure:"name_regex" cty:"name_regex" hcl:"name_regex"`
+	Type             *string  `mapstructure:"type" cty:"type" hcl:"type"`
+	Region           *string  `mapstructure:"region" cty:"region" hcl:"region"`
+	Latest           *bool    `mapstructure:"latest" cty:"latest" hcl:"latest"`
+	Tags             []string `mapstructure:"tags" cty:"tags" hcl:"tags"`
+	Distribution     *string  `mapstructure:"distribution" cty:"distribution" hcl:"distribution"`
+	Status           *string  `mapstructure:"status" cty:"status" hcl:"status"`
+	MinCreatedAt     *string  `mapstructure:"min_created_at" cty:"min_created_at" hcl:"min_created_at"`
+	MaxAge           *string  `mapstructure:"max_age" cty:"max_age" hcl:"max_age"`
+	MinDiskSize      *int     `mapstructure:"min_disk_size" cty:"min_disk_size" hcl:"min_disk_size"`
+	SortBy           *string  `mapstructure:"sort_by" cty:"sort_by" hcl:"sort_by"`
+	Limit            *int     `mapstructure:"limit" cty:"limit" hcl:"limit"`
+}
+
+// FlatMapstructure returns a new FlatConfig.
+// FlatConfig is an auto-generated flat version of Config.
+// Where the contents a fields with a `mapstructure:,squash` tag are bubbled up.
+func (*Config) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec } {
+	return new(FlatConfig)
+}
+
+// HCL2Spec returns the hcl spec of a Config.
+// This spec is used by HCL to read the fields of Config.
+// The decoded values from this spec will then be applied to a FlatConfig.
+func (*FlatConfig) HCL2Spec() map[string]hcldec.Spec {
+	s := map[string]hcldec.Spec{
+		"api_token":           &hcldec.AttrSpec{Name: "api_token", Type: cty.String, Required: false},
+		"api_url":             &hcldec.AttrSpec{Name: "api_url", Type: cty.String, Required: false},
+		"http_retry_max":      &hcldec.AttrSpec{Name: "http_retry_max", Type: cty.Number, Required: false},
+		"http_retry_wait_max": &hcldec.AttrSpec{Name: "http_retry_wait_max", Type: cty.Number, Required: false},
+		"http_retry_wait_min": &hcldec.AttrSpec{Name: "http_retry_wait_min", Type: cty.Number, Required: false},
+		"name":                &hcldec.AttrSpec{Name: "name", Type: cty.String, Required: false},
+		"name_regex":          &hcldec.AttrSpec{Name: "name_regex", Type: cty.String, Required: false},
+		"type":                &hcldec.AttrSpec{Name: "type", Type: cty.String, Required: false},
+		"region":              &hcldec.AttrSpec{Name: "region", Type: cty.String, Required: false},
+		"latest":              &hcldec.AttrSpec{Name: "latest", Type: cty.Bool, Required: false},
+		"tags":                &hcldec.AttrSpec{Name: "tags", Type: cty.List(cty.String), Required: false},
+		"distribution":        &hcldec.AttrSpec{Name: "distribution", Type: cty.String, Required: false},
+		"status":              &hcldec.AttrSpec{Name: "status", Type: cty.String, Required: false},
+		"min_created_at":      &hcldec.AttrSpec{Name: "min_created_at", Type: cty.String, Required: false},
+		"max_age":             &hcldec.AttrSpec{Name: "max_age", Type: cty.String, Required: false},
+		"min_disk_size":       &hcldec.AttrSpec{Name: "min_disk_size", Type: cty.Number, Required: false},
+		"sort_by":             &hcldec.AttrSpec{Name: "sort_by", Type: cty.String, Required: false},
+		"limit":               &hcldec.AttrSpec{Name: "limit", Type: cty.Number, Required: false},
+	}
+	return s
+}
+
+// FlatDatasourceOutput is an auto-generated flat version of DatasourceOutput.
+// Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
+type FlatDatasourceOutput struct {
+	Images []FlatImage `mapstructure:"images" cty:"images" hcl:"images"`
+}
+
+// FlatMapstructure returns a new FlatDatasourceOutput.
+// FlatDatasourceOutput is an auto-generated flat version of DatasourceOutput.
+// Where the contents a fields with a `mapstructure:,squash` tag are bubbled up.
+func (*DatasourceOutput) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec } {
+	return new(FlatDatasourceOutput)
+}
+
+// HCL2Spec returns the hcl spec of a DatasourceOutput.
+// This spec is used by HCL to read the fields of DatasourceOutput.
+// The decoded values from this spec will then be applied to a FlatDatasourceOutput.
+func (*FlatDatasourceOutput) HCL2Spec() map[string]hcldec.Spec {
+	s := map[string]hcldec.Spec{
+		"images": &hcldec.BlockListSpec{TypeName: "images", Nested: hcldec.ObjectSpec((*FlatImage)(nil).HCL2Spec())},
+	}
+	return s
+}
+
+// FlatImage is an auto-generated flat version of Image.
+// Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
+type FlatImage struct {
+	ID      *int     `mapstructure:"id" cty:"id" hcl:"id"`
+	Name    *string  `mapstructure:"name" cty:"name" hcl:"name"`
+	Regions []string `mapstructure:"regions" cty:"regions" hcl:"regions"`
+	Created *string  `mapstructure:"created" cty:"created" hcl:"created"`
+	Tags    []string `mapstructure:"tags" cty:"tags" hcl:"tags"`
+}
+
+// FlatMapstructure returns a new FlatImage.
+// FlatImage is an auto-generated flat version of Image.
+// Where the contents a fields with a `mapstructure:,squash` tag are bubbled up.
+func (*Image) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec } {
+	return new(FlatImage)
+}
+
+// HCL2Spec returns the hcl spec of a Image.
+// This spec is used by HCL to read the fields of Image.
+// The decoded values from this spec will then be applied to a FlatImage.
+func (*FlatImage) HCL2Spec() map[string]hcldec.Spec {
+	s := map[string]hcldec.Spec{
+		"id":      &hcldec.AttrSpec{Name: "id", Type: cty.Number, Required: false},
+		"name":    &hcldec.AttrSpec{Name: "name", Type: cty.String, Required: false},
+		"regions": &hcldec.AttrSpec{Name: "regions", Type: cty.List(cty.String), Required: false},
+		"created": &hcldec.AttrSpec{Name: "created", Type: cty.String, Required: false},
+		"tags":    &hcldec.AttrSpec{Name: "tags", Type: cty.List(cty.String), Required: false},
+	}
+	return s
+}
@@ -0,0 +1,80 @@
+package images
+
+import (
+	"testing"
+
+	"github.com/digitalocean/godo"
+	docommon "github.com/digitalocean/packer-plugin-digitalocean/common"
+	"github.com/hashicorp/packer-plugin-sdk/hcl2helper"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestDatasourceOutput(t *testing.T) {
+	images := []godo.Image{
+		{ID: 1, Name: "base-ubuntu", Created: "2022-08-08T21:31:54Z", Regions: []string{"nyc3"}, Tags: []string{"base"}},
+		{ID: 2, Name: "base-debian", Created: "2022-08-10T21:31:54Z", Regions: []string{"nyc3"}, Tags: []string{"base"}},
+		{ID: 3, Name: "base-fedora", Created: "2022-08-09T21:31:54Z", Regions: []string{"sfo3"}, Tags: []string{"base"}},
+		{ID: 4, Name: "app", Created: "2022-08-11T21:31:54Z", Regions: []string{"nyc3"}},
+	}
+
+	tests := []struct {
+		name        string
+		config      *Config
+		expectedIDs []int
+	}{
+		{
+			name:        "all images newest first",
+			config:      &Config{},
+			expectedIDs: []int{4, 2, 3, 1},
+		},
+		{
+			name: "filtered",
+			config: &Config{ImageFilterOptions: docommon.ImageFilterOptions{
+				NameRegex: "^base-",
+				Region:    "nyc3",
+			}},
+			expectedIDs: []int{2, 1},
+		},
+		{
+			name: "sorted by name with limit",
+			config: &Config{
+				ImageFilterOptions: docommon.ImageFilterOptions{Tags: []string{"base"}, SortBy: "name"},
+				Limit:              2,
+			},
+			expectedIDs: []int{1, 3},
+		},
+		{
+			name: "no matches",
+			config: &Config{ImageFilterOptions: docommon.ImageFilterOptions{
+				Name: "missing",
+			}},
+			expectedIDs: []int{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output := newDatasourceOutput(tt.config, images)
+			ids := make([]int, 0)
+			for _, i := range output.Images {
+				ids = append(ids, i.ID)
+			}
+			require.Equal(t, tt.expectedIDs, ids)
+		})
+	}
+}
+
+func TestDatasourceOutput_HCL2Value(t *testing.T) {
+	output := newDatasourceOutput(&Config{}, []godo.Image{
+		{ID: 1, Name: "base-ubuntu", Created: "2022-08-08T21:31:54Z", Regions: []string{"nyc3"}, Tags: []string{"base"}},
+	})
+
+	d := &Datasource{}
+	val := hcl2helper.HCL2ValueFromConfig(output, d.OutputSpec())
+	images := val.GetAttr("images")
+	require.Equal(t, 1, images.LengthInt())
+	image := images.Index(cty.NumberIntVal(0))
+	require.Equal(t, cty.NumberIntVal(1), image.GetAttr("id"))
+	require.Equal(t, cty.StringVal("base-ubuntu"), image.GetAttr("name"))
+}
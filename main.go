@@ -6,7 +6,10 @@ import (
 
 	"github.com/digitalocean/packer-plugin-digitalocean/builder/digitalocean"
 	"github.com/digitalocean/packer-plugin-digitalocean/datasource/image"
+	"github.com/digitalocean/packer-plugin-digitalocean/datasource/images"
+	digitaloceancleanup "github.com/digitalocean/packer-plugin-digitalocean/post-processor/digitalocean-cleanup"
 	digitaloceanPP "github.com/digitalocean/packer-plugin-digitalocean/post-processor/digitalocean-import"
+	digitaloceanretention "github.com/digitalocean/packer-plugin-digitalocean/post-processor/digitalocean-retention"
 	"github.com/digitalocean/packer-plugin-digitalocean/version"
 
 	"github.com/hashicorp/packer-plugin-sdk/plugin"
@@ -16,7 +19,10 @@ func main() {
 	pps := plugin.NewSet()
 	pps.RegisterBuilder(plugin.DEFAULT_NAME, new(digitalocean.Builder))
 	pps.RegisterPostProcessor("import", new(digitaloceanPP.PostProcessor))
+	pps.RegisterPostProcessor("retention", new(digitaloceanretention.PostProcessor))
+	pps.RegisterPostProcessor("cleanup", new(digitaloceancleanup.PostProcessor))
 	pps.RegisterDatasource("image", new(image.Datasource))
+	pps.RegisterDatasource("images", new(images.Datasource))
 	pps.SetVersion(version.PluginVersion)
 	err := pps.Run()
 	if err != nil {
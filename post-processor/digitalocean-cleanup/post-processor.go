@@ -0,0 +1,402 @@
+//go:generate packer-sdc struct-markdown
+//go:generate packer-sdc mapstructure-to-hcl2 -type Config
+
+package digitaloceancleanup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/digitalocean/godo"
+
+	docommon "github.com/digitalocean/packer-plugin-digitalocean/common"
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/packer-plugin-sdk/common"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+)
+
+const BuilderId = "packer.post-processor.digitalocean-cleanup"
+
+// createdAtTagPrefix starts the `packer-created-at:<unix timestamp>` tags
+// applied by earlier versions of the builder, one per build. They are
+// removed once no resource uses them.
+const createdAtTagPrefix = "packer-created-at:"
+
+type Config struct {
+	common.PackerConfig   `mapstructure:",squash"`
+	docommon.AccessConfig `mapstructure:",squash"`
+
+	// Only remove resources created longer ago than this duration. Resources
+	// of builds still running must be younger than this. Defaults to `24h`.
+	MaxAge time.Duration `mapstructure:"max_age"`
+	// Only report the resources that would be removed, without removing
+	// them. Defaults to `false`.
+	DryRun bool `mapstructure:"dry_run"`
+}
+
+type PostProcessor struct {
+	config Config
+}
+
+// resource is a Packer-owned resource found by the sweeper.
+type resource struct {
+	kind    string
+	id      string
+	name    string
+	created time.Time
+	delete  func(ctx context.Context) error
+}
+
+func (p *PostProcessor) ConfigSpec() hcldec.ObjectSpec { return p.config.FlatMapstructure().HCL2Spec() }
+
+func (p *PostProcessor) Configure(raws ...interface{}) error {
+	err := config.Decode(&p.config, &config.DecodeOpts{
+		PluginType:  BuilderId,
+		Interpolate: true,
+	}, raws...)
+	if err != nil {
+		return err
+	}
+
+	if p.config.MaxAge == 0 {
+		p.config.MaxAge = 24 * time.Hour
+	}
+
+	errs := new(packersdk.MultiError)
+
+	warns, es := p.config.AccessConfig.Prepare()
+	for _, w := range warns {
+		log.Printf("[WARN] %s", w)
+	}
+	if len(es) > 0 {
+		errs = packersdk.MultiErrorAppend(errs, es...)
+	}
+
+	if p.config.MaxAge < 0 {
+		errs = packersdk.MultiErrorAppend(
+			errs, errors.New("max_age must be positive"))
+	}
+
+	if len(errs.Errors) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func (p *PostProcessor) PostProcess(ctx context.Context, ui packersdk.Ui, artifact packersdk.Artifact) (packersdk.Artifact, bool, bool, error) {
+	client, err := p.config.Client()
+	if err != nil {
+		return nil, false, false, fmt.Errorf("DigitalOcean: could not create client, %s", err)
+	}
+
+	ui.Say(fmt.Sprintf("Looking for Packer resources older than %s...", p.config.MaxAge))
+	resources, err := findResources(ctx, client)
+	if err != nil {
+		return nil, false, false, err
+	}
+
+	expired := expiredResources(resources, p.config.MaxAge, time.Now())
+	if len(expired) == 0 {
+		ui.Say("No Packer resources to remove")
+		return artifact, true, false, nil
+	}
+
+	var errs *packersdk.MultiError
+	for _, r := range expired {
+		if p.config.DryRun {
+			ui.Message(fmt.Sprintf("Would remove %s: %s (ID: %s, created %s)",
+				r.kind, r.name, r.id, r.created.Format(time.RFC3339)))
+			continue
+		}
+
+		ui.Message(fmt.Sprintf("Removing %s: %s (ID: %s, created %s)",
+			r.kind, r.name, r.id, r.created.Format(time.RFC3339)))
+		if err := r.delete(ctx); err != nil {
+			errs = packersdk.MultiErrorAppend(errs,
+				fmt.Errorf("Error removing %s %s: %s", r.kind, r.id, err))
+		}
+	}
+
+	if errs != nil && len(errs.Errors) > 0 {
+		return nil, false, false, errs
+	}
+
+	return artifact, true, false, nil
+}
+
+// findResources lists the droplets, volumes, SSH keys, firewalls, and
+// firewall tags created by the builder. Droplets are listed first so that
+// they are removed before the resources they use.
+func findResources(ctx context.Context, client *godo.Client) ([]resource, error) {
+	var resources []resource
+
+	droplets, err := listDroplets(ctx, client)
+	if err != nil {
+		return nil, fmt.Errorf("Error listing droplets: %s", err)
+	}
+	for _, d := range droplets {
+		d := d
+		created, _ := time.Parse(time.RFC3339, d.Created)
+		resources = append(resources, resource{
+			kind:    "droplet",
+			id:      strconv.Itoa(d.ID),
+			name:    d.Name,
+			created: created,
+			delete: func(ctx context.Context) error {
+				_, err := client.Droplets.Delete(ctx, d.ID)
+				return err
+			},
+		})
+	}
+
+	volumes, err := listVolumes(ctx, client)
+	if err != nil {
+		return nil, fmt.Errorf("Error listing volumes: %s", err)
+	}
+	for _, v := range volumes {
+		v := v
+		// Volumes are detached some time after their droplet is removed, and
+		// are picked up by the next run.
+		if !contains(v.Tags, docommon.BuildTag) || len(v.DropletIDs) > 0 {
+			continue
+		}
+		resources = append(resources, resource{
+			kind:    "volume",
+			id:      v.ID,
+			name:    v.Name,
+			created: v.CreatedAt,
+			delete: func(ctx context.Context) error {
+				_, err := client.Storage.DeleteVolume(ctx, v.ID)
+				return err
+			},
+		})
+	}
+
+	keys, err := listKeys(ctx, client)
+	if err != nil {
+		return nil, fmt.Errorf("Error listing SSH keys: %s", err)
+	}
+	for _, k := range keys {
+		k := k
+		created, ok := docommon.ParseResourceName(k.Name)
+		if !ok {
+			continue
+		}
+		resources = append(resources, resource{
+			kind:    "SSH key",
+			id:      strconv.Itoa(k.ID),
+			name:    k.Name,
+			created: created,
+			delete: func(ctx context.Context) error {
+				_, err := client.Keys.DeleteByID(ctx, k.ID)
+				return err
+			},
+		})
+	}
+
+	firewalls, err := listFirewalls(ctx, client)
+	if err != nil {
+		return nil, fmt.Errorf("Error listing firewalls: %s", err)
+	}
+	for _, f := range firewalls {
+		f := f
+		created, ok := docommon.ParseResourceName(f.Name)
+		if !ok {
+			continue
+		}
+		resources = append(resources, resource{
+			kind:    "firewall",
+			id:      f.ID,
+			name:    f.Name,
+			created: created,
+			delete: func(ctx context.Context) error {
+				_, err := client.Firewalls.Delete(ctx, f.ID)
+				return err
+			},
+		})
+	}
+
+	tags, err := listTags(ctx, client)
+	if err != nil {
+		return nil, fmt.Errorf("Error listing tags: %s", err)
+	}
+	for _, t := range tags {
+		t := t
+		created, ok := docommon.ParseResourceName(t.Name)
+		if !ok {
+			created, ok = parseCreatedAtTag(t)
+		}
+		if !ok {
+			continue
+		}
+		resources = append(resources, resource{
+			kind:    "tag",
+			id:      t.Name,
+			name:    t.Name,
+			created: created,
+			delete: func(ctx context.Context) error {
+				_, err := client.Tags.Delete(ctx, t.Name)
+				return err
+			},
+		})
+	}
+
+	return resources, nil
+}
+
+// parseCreatedAtTag returns the creation time recorded in an unused
+// `packer-created-at:<unix timestamp>` tag.
+func parseCreatedAtTag(t godo.Tag) (time.Time, bool) {
+	if !strings.HasPrefix(t.Name, createdAtTagPrefix) {
+		return time.Time{}, false
+	}
+	if t.Resources != nil && t.Resources.Count > 0 {
+		return time.Time{}, false
+	}
+
+	unix, err := strconv.ParseInt(strings.TrimPrefix(t.Name, createdAtTagPrefix), 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return time.Unix(unix, 0).UTC(), true
+}
+
+// expiredResources returns the resources created longer than maxAge ago.
+// Resources whose creation time is unknown are never returned.
+func expiredResources(resources []resource, maxAge time.Duration, now time.Time) []resource {
+	expired := make([]resource, 0)
+	for _, r := range resources {
+		if r.created.IsZero() {
+			log.Printf("[WARN] Skipping %s %s with unknown creation time", r.kind, r.id)
+			continue
+		}
+
+		if now.Sub(r.created) > maxAge {
+			expired = append(expired, r)
+		}
+	}
+
+	return expired
+}
+
+func listDroplets(ctx context.Context, client *godo.Client) ([]godo.Droplet, error) {
+	opt := &godo.ListOptions{Page: 1, PerPage: 200}
+	var list []godo.Droplet
+	for {
+		droplets, resp, err := client.Droplets.ListByTag(ctx, docommon.BuildTag, opt)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, droplets...)
+
+		if resp.Links == nil || resp.Links.IsLastPage() {
+			return list, nil
+		}
+		page, err := resp.Links.CurrentPage()
+		if err != nil {
+			return nil, err
+		}
+		opt.Page = page + 1
+	}
+}
+
+func listVolumes(ctx context.Context, client *godo.Client) ([]godo.Volume, error) {
+	params := &godo.ListVolumeParams{ListOptions: &godo.ListOptions{Page: 1, PerPage: 200}}
+	var list []godo.Volume
+	for {
+		volumes, resp, err := client.Storage.ListVolumes(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, volumes...)
+
+		if resp.Links == nil || resp.Links.IsLastPage() {
+			return list, nil
+		}
+		page, err := resp.Links.CurrentPage()
+		if err != nil {
+			return nil, err
+		}
+		params.ListOptions.Page = page + 1
+	}
+}
+
+func listKeys(ctx context.Context, client *godo.Client) ([]godo.Key, error) {
+	opt := &godo.ListOptions{Page: 1, PerPage: 200}
+	var list []godo.Key
+	for {
+		keys, resp, err := client.Keys.List(ctx, opt)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, keys...)
+
+		if resp.Links == nil || resp.Links.IsLastPage() {
+			return list, nil
+		}
+		page, err := resp.Links.CurrentPage()
+		if err != nil {
+			return nil, err
+		}
+		opt.Page = page + 1
+	}
+}
+
+func listFirewalls(ctx context.Context, client *godo.Client) ([]godo.Firewall, error) {
+	opt := &godo.ListOptions{Page: 1, PerPage: 200}
+	var list []godo.Firewall
+	for {
+		firewalls, resp, err := client.Firewalls.List(ctx, opt)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, firewalls...)
+
+		if resp.Links == nil || resp.Links.IsLastPage() {
+			return list, nil
+		}
+		page, err := resp.Links.CurrentPage()
+		if err != nil {
+			return nil, err
+		}
+		opt.Page = page + 1
+	}
+}
+
+func listTags(ctx context.Context, client *godo.Client) ([]godo.Tag, error) {
+	opt := &godo.ListOptions{Page: 1, PerPage: 200}
+	var list []godo.Tag
+	for {
+		tags, resp, err := client.Tags.List(ctx, opt)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, tags...)
+
+		if resp.Links == nil || resp.Links.IsLastPage() {
+			return list, nil
+		}
+		page, err := resp.Links.CurrentPage()
+		if err != nil {
+			return nil, err
+		}
+		opt.Page = page + 1
+	}
+}
+
+func contains(list []string, term string) bool {
+	for _, t := range list {
+		if t == term {
+			return true
+		}
+	}
+	return false
+}
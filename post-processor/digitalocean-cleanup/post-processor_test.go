@@ -0,0 +1,101 @@
+package digitaloceancleanup
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/digitalocean/godo"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostProcessor_ImplementsPostProcessor(t *testing.T) {
+	var _ packersdk.PostProcessor = new(PostProcessor)
+}
+
+func TestPostProcessor_Configure(t *testing.T) {
+	var p PostProcessor
+	err := p.Configure(map[string]interface{}{"api_token": "foo"})
+	require.NoError(t, err)
+	require.Equal(t, 24*time.Hour, p.config.MaxAge)
+
+	p = PostProcessor{}
+	err = p.Configure(map[string]interface{}{"api_token": "foo", "max_age": "-1h"})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "max_age must be positive")
+}
+
+func TestFindResources(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/droplets":
+			assert.Equal(t, "packer-build", r.URL.Query().Get("tag_name"))
+			_, _ = w.Write([]byte(`{"droplets":[
+				{"id":1,"name":"packer-1","tags":["packer-build"],"created_at":"2022-08-10T21:31:54Z"},
+				{"id":2,"name":"packer-2","tags":["packer-build"],"created_at":"2022-08-11T00:00:00Z"}
+			]}`))
+		case "/v2/volumes":
+			_, _ = w.Write([]byte(`{"volumes":[
+				{"id":"v1","name":"packer-volume-1","tags":["packer-build"],"created_at":"2022-08-10T21:31:54Z"},
+				{"id":"v2","name":"packer-volume-2","tags":["packer-build"],"droplet_ids":[1]},
+				{"id":"v3","name":"data","tags":["web"]}
+			]}`))
+		case "/v2/account/keys":
+			_, _ = w.Write([]byte(`{"ssh_keys":[
+				{"id":10,"name":"packer-62f423ca-1c8e-5b2f-9d3a-0a1b2c3d4e5f"},
+				{"id":11,"name":"laptop"}
+			]}`))
+		case "/v2/firewalls":
+			_, _ = w.Write([]byte(`{"firewalls":[
+				{"id":"f1","name":"packer-62f423ca-1c8e-5b2f-9d3a-0a1b2c3d4e5f"},
+				{"id":"f2","name":"web"}
+			]}`))
+		case "/v2/tags":
+			_, _ = w.Write([]byte(`{"tags":[
+				{"name":"packer-62f423ca-1c8e-5b2f-9d3a-0a1b2c3d4e5f"},
+				{"name":"packer-build","resources":{"count":2}},
+				{"name":"packer-created-at:1660167114","resources":{"count":0}},
+				{"name":"packer-created-at:1660170000","resources":{"count":1}}
+			]}`))
+		default:
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	client := godo.NewClient(nil)
+	client.BaseURL, _ = url.Parse(server.URL)
+
+	resources, err := findResources(context.Background(), client)
+	require.NoError(t, err)
+
+	var found []string
+	for _, r := range resources {
+		found = append(found, r.kind+":"+r.id)
+	}
+	require.Equal(t, []string{"droplet:1", "droplet:2", "volume:v1", "SSH key:10", "firewall:f1",
+		"tag:packer-62f423ca-1c8e-5b2f-9d3a-0a1b2c3d4e5f", "tag:packer-created-at:1660167114"}, found)
+	require.Equal(t, time.Date(2022, 8, 10, 21, 31, 54, 0, time.UTC), resources[0].created)
+	require.Equal(t, time.Date(2022, 8, 11, 0, 0, 0, 0, time.UTC), resources[1].created)
+	require.Equal(t, time.Date(2022, 8, 10, 21, 31, 54, 0, time.UTC), resources[2].created)
+	require.Equal(t, time.Date(2022, 8, 10, 21, 31, 54, 0, time.UTC), resources[6].created)
+}
+
+func TestExpiredResources(t *testing.T) {
+	now := time.Date(2022, 8, 11, 0, 0, 0, 0, time.UTC)
+	resources := []resource{
+		{kind: "droplet", id: "1", created: now.Add(-48 * time.Hour)},
+		{kind: "droplet", id: "2", created: now.Add(-time.Hour)},
+		{kind: "droplet", id: "3"},
+	}
+
+	expired := expiredResources(resources, 24*time.Hour, now)
+	require.Len(t, expired, 1)
+	require.Equal(t, "1", expired[0].id)
+}
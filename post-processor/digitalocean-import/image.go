@@ -0,0 +1,239 @@
+package digitaloceanimport
+
+import (
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// maxImageSize is the largest uncompressed image DigitalOcean imports.
+var maxImageSize int64 = 100 * 1024 * 1024 * 1024
+
+// imageFormats are the disk image formats DigitalOcean imports.
+var imageFormats = []string{"raw", "qcow2", "vhdx", "vdi", "vmdk"}
+
+// imageCompressions are the compressions DigitalOcean imports.
+var imageCompressions = []string{"gzip", "bzip2"}
+
+// compressedSuffixes are the suffixes of already compressed artifacts.
+var compressedSuffixes = []string{".gz", ".bz2", ".xz"}
+
+// qemuImgInfo is the output of `qemu-img info --output=json`.
+type qemuImgInfo struct {
+	Format      string `json:"format"`
+	VirtualSize int64  `json:"virtual-size"`
+}
+
+// prepareImage converts and compresses the image as configured, writing
+// the files it creates in dir, and returns the path of the file to upload.
+// The size of the uncompressed image is checked against the DigitalOcean
+// limit before anything else is done with it.
+func prepareImage(ctx context.Context, ui packersdk.Ui, p *PostProcessor, source string, dir string) (string, error) {
+	if hasSuffix(source, compressedSuffixes) {
+		if p.config.ImageFormat != "" {
+			return "", fmt.Errorf("Can not convert the compressed image %s", source)
+		}
+
+		size, err := uncompressedSize(source)
+		if err != nil {
+			return "", fmt.Errorf("Failed to read the compressed image %s: %s", source, err)
+		}
+		if size < 0 {
+			log.Printf("Can not read the size of %s, skipping the size check", source)
+		} else if err := checkImageSize(source, size); err != nil {
+			return "", err
+		}
+
+		log.Printf("%s is already compressed, skipping compression", source)
+		return source, nil
+	}
+
+	stat, err := os.Stat(source)
+	if err != nil {
+		return "", err
+	}
+	size := stat.Size()
+
+	var info *qemuImgInfo
+	if p.config.ImageFormat != "" {
+		info, err = imageInfo(ctx, p.config.QemuImgPath, source)
+		if err != nil {
+			return "", err
+		}
+
+		// A converted image can grow up to the size of the virtual disk
+		if info.Format != p.config.ImageFormat && info.VirtualSize > size {
+			size = info.VirtualSize
+		}
+	}
+
+	if err := checkImageSize(source, size); err != nil {
+		return "", err
+	}
+
+	if info != nil && info.Format != p.config.ImageFormat {
+		target := filepath.Join(dir, strings.TrimSuffix(filepath.Base(source), filepath.Ext(source))+"."+p.config.ImageFormat)
+		ui.Message(fmt.Sprintf("Converting %s from %s to %s", source, info.Format, p.config.ImageFormat))
+		if err := convertImage(ctx, p.config.QemuImgPath, source, target, p.config.ImageFormat); err != nil {
+			return "", err
+		}
+		source = target
+	}
+
+	switch p.config.ImageCompression {
+	case "gzip":
+		target := filepath.Join(dir, filepath.Base(source)+".gz")
+		ui.Message(fmt.Sprintf("Compressing %s with gzip", source))
+		if err := gzipFile(source, target); err != nil {
+			return "", fmt.Errorf("Failed to compress %s: %s", source, err)
+		}
+		source = target
+	case "bzip2":
+		target := filepath.Join(dir, filepath.Base(source)+".bz2")
+		ui.Message(fmt.Sprintf("Compressing %s with bzip2", source))
+		if err := bzip2File(ctx, source, target); err != nil {
+			return "", fmt.Errorf("Failed to compress %s: %s", source, err)
+		}
+		source = target
+	}
+
+	return source, nil
+}
+
+// checkImageSize returns an error if an image of size bytes is larger than
+// DigitalOcean imports.
+func checkImageSize(source string, size int64) error {
+	if size > maxImageSize {
+		return fmt.Errorf("Image %s is %d bytes uncompressed, more than the %d bytes DigitalOcean imports",
+			source, size, maxImageSize)
+	}
+
+	return nil
+}
+
+// uncompressedSize returns the size of the decompressed content of a gzip or
+// bzip2 compressed file, reading no more than one byte past maxImageSize, or
+// -1 for other compressions.
+func uncompressedSize(source string) (int64, error) {
+	f, err := os.Open(source)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	var r io.Reader
+	switch {
+	case strings.HasSuffix(source, ".gz"):
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return 0, err
+		}
+		r = gz
+	case strings.HasSuffix(source, ".bz2"):
+		r = bzip2.NewReader(f)
+	default:
+		return -1, nil
+	}
+
+	n, err := io.CopyN(io.Discard, r, maxImageSize+1)
+	if err != nil && err != io.EOF {
+		return 0, err
+	}
+
+	return n, nil
+}
+
+// imageInfo returns the format and virtual size of the image.
+func imageInfo(ctx context.Context, qemuImg string, source string) (*qemuImgInfo, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, qemuImg, "info", "--output=json", source)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("Failed to read the format of %s: %s: %s", source, err, strings.TrimSpace(stderr.String()))
+	}
+
+	var info qemuImgInfo
+	if err := json.Unmarshal(stdout.Bytes(), &info); err != nil {
+		return nil, fmt.Errorf("Failed to parse qemu-img info output: %s", err)
+	}
+
+	return &info, nil
+}
+
+// convertImage converts the image to the format with qemu-img.
+func convertImage(ctx context.Context, qemuImg string, source, target, format string) error {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, qemuImg, "convert", "-O", format, source, target)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("Failed to convert %s to %s: %s: %s", source, format, err, strings.TrimSpace(stderr.String()))
+	}
+
+	return nil
+}
+
+// gzipFile writes the gzip compressed content of source to target.
+func gzipFile(source, target string) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	w := gzip.NewWriter(out)
+	if _, err := io.Copy(w, in); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return out.Close()
+}
+
+// bzip2File writes the bzip2 compressed content of source to target with
+// the bzip2 command, as the standard library can only decompress bzip2.
+func bzip2File(ctx context.Context, source, target string) error {
+	out, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "bzip2", "--compress", "--stdout", source)
+	cmd.Stdout = out
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return out.Close()
+}
+
+func hasSuffix(path string, suffixes []string) bool {
+	for _, suffix := range suffixes {
+		if strings.HasSuffix(path, suffix) {
+			return true
+		}
+	}
+	return false
+}
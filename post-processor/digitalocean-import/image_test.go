@@ -0,0 +1,278 @@
+package digitaloceanimport
+
+import (
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMain runs the test binary as a fake qemu-img when
+// GO_WANT_HELPER_PROCESS is set, so that conversions are tested without
+// qemu-img or a shell.
+func TestMain(m *testing.M) {
+	if os.Getenv("GO_WANT_HELPER_PROCESS") == "1" {
+		os.Exit(fakeQemuImg(os.Args[1:]))
+	}
+
+	os.Exit(m.Run())
+}
+
+// fakeQemuImg is a qemu-img reporting every image as raw, with the virtual
+// size in FAKE_QEMU_IMG_VIRTUAL_SIZE or else the size of the file, and
+// converting images by appending a marker to them.
+func fakeQemuImg(args []string) int {
+	switch args[0] {
+	case "info":
+		stat, err := os.Stat(args[len(args)-1])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		size := strconv.FormatInt(stat.Size(), 10)
+		if s := os.Getenv("FAKE_QEMU_IMG_VIRTUAL_SIZE"); s != "" {
+			size = s
+		}
+		fmt.Printf(`{"format": "raw", "virtual-size": %s}`, size)
+	case "convert":
+		content, err := os.ReadFile(args[len(args)-2])
+		if err == nil {
+			err = os.WriteFile(args[len(args)-1], append(content, "converted"...), 0644)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	default:
+		return 1
+	}
+
+	return 0
+}
+
+// testPrepareImage prepares an image file with the content of
+// testImageContent, gzip compressed if name ends with .gz, writing the files it creates in dir.
+func testPrepareImage(t *testing.T, config map[string]interface{}, name string, dir string) (string, error) {
+	qemuImg, err := os.Executable()
+	require.NoError(t, err)
+	t.Setenv("GO_WANT_HELPER_PROCESS", "1")
+
+	content := testImageContent
+	if strings.HasSuffix(name, ".gz") {
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		_, _ = w.Write(content)
+		require.NoError(t, w.Close())
+		content = buf.Bytes()
+	}
+
+	source := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(source, content, 0644))
+
+	c := testConfig()
+	c["qemu_img_path"] = qemuImg
+	for k, v := range config {
+		c[k] = v
+	}
+
+	var p PostProcessor
+	require.NoError(t, p.Configure(c))
+
+	return prepareImage(context.Background(), packersdk.TestUi(t), &p, source, dir)
+}
+
+func readFile(t *testing.T, path string, decompress func(io.Reader) (io.Reader, error)) []byte {
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	r, err := decompress(f)
+	require.NoError(t, err)
+	content, err := io.ReadAll(r)
+	require.NoError(t, err)
+
+	return content
+}
+
+func TestPrepareImage(t *testing.T) {
+	t.Run("unchanged", func(t *testing.T) {
+		image, err := testPrepareImage(t, nil, "disk.raw", t.TempDir())
+		require.NoError(t, err)
+		require.Equal(t, "disk.raw", filepath.Base(image))
+	})
+
+	t.Run("same format", func(t *testing.T) {
+		image, err := testPrepareImage(t, map[string]interface{}{"image_format": "raw"}, "disk.raw", t.TempDir())
+		require.NoError(t, err)
+		require.Equal(t, "disk.raw", filepath.Base(image))
+	})
+
+	t.Run("convert and gzip", func(t *testing.T) {
+		image, err := testPrepareImage(t, map[string]interface{}{
+			"image_format":      "qcow2",
+			"image_compression": "gzip",
+		}, "disk.raw", t.TempDir())
+		require.NoError(t, err)
+		require.Equal(t, "disk.qcow2.gz", filepath.Base(image))
+
+		content := readFile(t, image, func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) })
+		require.Equal(t, append(append([]byte{}, testImageContent...), "converted"...), content)
+	})
+
+	t.Run("bzip2", func(t *testing.T) {
+		if _, err := exec.LookPath("bzip2"); err != nil {
+			t.Skip("bzip2 is not installed")
+		}
+
+		image, err := testPrepareImage(t, map[string]interface{}{"image_compression": "bzip2"}, "disk.raw", t.TempDir())
+		require.NoError(t, err)
+		require.Equal(t, "disk.raw.bz2", filepath.Base(image))
+
+		content := readFile(t, image, func(r io.Reader) (io.Reader, error) { return bzip2.NewReader(r), nil })
+		require.Equal(t, testImageContent, content)
+	})
+
+	t.Run("already compressed", func(t *testing.T) {
+		image, err := testPrepareImage(t, map[string]interface{}{"image_compression": "gzip"}, "disk.raw.gz", t.TempDir())
+		require.NoError(t, err)
+		require.Equal(t, "disk.raw.gz", filepath.Base(image))
+
+		_, err = testPrepareImage(t, map[string]interface{}{"image_format": "qcow2"}, "disk.raw.gz", t.TempDir())
+		require.Error(t, err)
+	})
+
+	t.Run("too large", func(t *testing.T) {
+		defer func(size int64) { maxImageSize = size }(maxImageSize)
+		maxImageSize = int64(len(testImageContent)) - 1
+
+		for _, name := range []string{"disk.raw", "disk.raw.gz"} {
+			dir := t.TempDir()
+			_, err := testPrepareImage(t, map[string]interface{}{"image_compression": "gzip"}, name, dir)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), "1024 bytes uncompressed, more than the 1023 bytes")
+
+			files, err := os.ReadDir(dir)
+			require.NoError(t, err)
+			require.Empty(t, files)
+		}
+	})
+
+	t.Run("virtual size too large", func(t *testing.T) {
+		defer func(size int64) { maxImageSize = size }(maxImageSize)
+		maxImageSize = 2048
+		t.Setenv("FAKE_QEMU_IMG_VIRTUAL_SIZE", "4096")
+
+		// The size is checked before converting the image
+		dir := t.TempDir()
+		_, err := testPrepareImage(t, map[string]interface{}{"image_format": "qcow2"}, "disk.raw", dir)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "4096 bytes uncompressed")
+
+		files, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		require.Empty(t, files)
+
+		// The virtual size does not matter when the image is not converted
+		_, err = testPrepareImage(t, map[string]interface{}{"image_format": "raw"}, "disk.raw", t.TempDir())
+		require.NoError(t, err)
+	})
+}
+
+func TestPostProcessor_Configure_ImageFormat(t *testing.T) {
+	tests := map[string]map[string]interface{}{
+		"invalid format":      {"image_format": "iso"},
+		"invalid compression": {"image_compression": "xz"},
+		"with image_url": {
+			"image_url":         "https://cloud-images.example.com/jammy.img",
+			"image_compression": "gzip",
+		},
+	}
+
+	for name, extra := range tests {
+		t.Run(name, func(t *testing.T) {
+			config := testConfig()
+			if _, ok := extra["image_url"]; ok {
+				delete(config, "space_name")
+				delete(config, "space_object_name")
+			}
+			for k, v := range extra {
+				config[k] = v
+			}
+
+			var p PostProcessor
+			require.Error(t, p.Configure(config))
+		})
+	}
+}
+
+func TestPostProcessor_Configure_Commands(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+
+	tests := map[string]struct {
+		extra    map[string]interface{}
+		expected string
+	}{
+		"qemu-img": {
+			extra:    map[string]interface{}{"image_format": "qcow2"},
+			expected: "image_format requires qemu-img",
+		},
+		"qemu_img_path": {
+			extra: map[string]interface{}{
+				"image_format":  "qcow2",
+				"qemu_img_path": filepath.Join(t.TempDir(), "qemu-img"),
+			},
+			expected: "image_format requires qemu-img",
+		},
+		"bzip2": {
+			extra:    map[string]interface{}{"image_compression": "bzip2"},
+			expected: "image_compression bzip2 requires the bzip2 command",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			config := testConfig()
+			for k, v := range tt.extra {
+				config[k] = v
+			}
+
+			var p PostProcessor
+			err := p.Configure(config)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tt.expected)
+		})
+	}
+
+	// gzip is compressed without any command
+	config := testConfig()
+	config["image_compression"] = "gzip"
+	var p PostProcessor
+	require.NoError(t, p.Configure(config))
+}
+
+func TestPostProcessor_PostProcess_Compression(t *testing.T) {
+	config := testConfig()
+	config["image_compression"] = "gzip"
+	config["skip_clean"] = true
+
+	spaces := newFakeSpaces()
+	_, _, err := testImport(t, spaces, config)
+	require.NoError(t, err)
+
+	r, err := gzip.NewReader(bytes.NewReader(spaces.objects["/bucket/image.raw"].body))
+	require.NoError(t, err)
+	content, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.Equal(t, testImageContent, content)
+}
@@ -5,16 +5,24 @@ package digitaloceanimport
 
 import (
 	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"net/http"
+	"net/url"
 	"os"
-	"strconv"
+	"os/exec"
+	"path"
+	"path/filepath"
 	"strings"
 	"time"
 
-	"golang.org/x/oauth2"
-
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
@@ -22,53 +30,90 @@ import (
 	"github.com/digitalocean/godo"
 
 	"github.com/digitalocean/packer-plugin-digitalocean/builder/digitalocean"
-	"github.com/digitalocean/packer-plugin-digitalocean/version"
+	docommon "github.com/digitalocean/packer-plugin-digitalocean/common"
 	"github.com/hashicorp/hcl/v2/hcldec"
 	"github.com/hashicorp/packer-plugin-sdk/common"
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
 	"github.com/hashicorp/packer-plugin-sdk/template/config"
 	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
-	"github.com/hashicorp/packer-plugin-sdk/useragent"
 )
 
 const BuilderId = "packer.post-processor.digitalocean-import"
 
+// checksumMetadataKey is the object metadata holding the SHA256 checksum of
+// uploaded images.
+const checksumMetadataKey = "Sha256"
+
 type Config struct {
-	common.PackerConfig `mapstructure:",squash"`
+	common.PackerConfig   `mapstructure:",squash"`
+	docommon.AccessConfig `mapstructure:",squash"`
 
-	// A personal access token used to communicate with the DigitalOcean v2 API.
-	// This may also be set using the `DIGITALOCEAN_TOKEN` or
-	// `DIGITALOCEAN_ACCESS_TOKEN` environmental variables.
-	APIToken string `mapstructure:"api_token" required:"true"`
 	// The access key used to communicate with Spaces. This may also be set using
 	// the `DIGITALOCEAN_SPACES_ACCESS_KEY` environmental variable.
 	SpacesKey string `mapstructure:"spaces_key" required:"true"`
 	// The secret key used to communicate with Spaces. This may also be set using
 	// the `DIGITALOCEAN_SPACES_SECRET_KEY` environmental variable.
 	SpacesSecret string `mapstructure:"spaces_secret" required:"true"`
-	// The maximum number of retries for requests that fail with a 429 or 500-level error.
-	// The default value is 5. Set to 0 to disable reties.
-	HTTPRetryMax *int `mapstructure:"http_retry_max" required:"false"`
-	// The maximum wait time (in seconds) between failed API requests. Default: 30.0
-	HTTPRetryWaitMax *float64 `mapstructure:"http_retry_wait_max" required:"false"`
-	// The minimum wait time (in seconds) between failed API requests. Default: 1.0
-	HTTPRetryWaitMin *float64 `mapstructure:"http_retry_wait_min" required:"false"`
 	// The name of the region, such as `nyc3`, in which to upload the image to Spaces.
 	SpacesRegion string `mapstructure:"spaces_region" required:"true"`
 	// The name of the specific Space where the image file will be copied to for
 	// import. This Space must exist when the post-processor is run.
 	SpaceName string `mapstructure:"space_name" required:"true"`
+	// The URL of the Spaces API, such as `https://nyc3.digitaloceanspaces.com`.
+	// This may be set to use another S3-compatible storage. The image is
+	// imported from the object URL built from this endpoint, so it must be
+	// reachable by DigitalOcean. Defaults to
+	// `https://<spaces_region>.digitaloceanspaces.com`.
+	SpacesEndpoint string `mapstructure:"spaces_endpoint"`
+	// Whether objects are addressed as `<spaces_endpoint>/<space_name>/<key>`
+	// instead of `<space_name>.<spaces_endpoint host>/<key>`. Some
+	// S3-compatible storages only support this path-style addressing.
+	// Defaults to `false`.
+	SpacesForcePathStyle bool `mapstructure:"spaces_force_path_style"`
+	// The canned ACL of the uploaded image file, `private` or `public-read`.
+	// A private object is imported through a presigned URL valid for
+	// `timeout`. Defaults to `private`.
+	SpacesACL string `mapstructure:"spaces_acl"`
+	// The size in MiB of the parts of multipart uploads to Spaces. It is
+	// raised when needed to stay within the limit of 10000 parts. Defaults
+	// to `5`, the minimum.
+	SpacesUploadPartSize int64 `mapstructure:"spaces_upload_part_size"`
+	// The number of parts uploaded to Spaces in parallel. Defaults to `5`.
+	SpacesUploadConcurrency int `mapstructure:"spaces_upload_concurrency"`
 	// The name of the key used in the Space where the image file will be copied
 	// to for import. This is treated as a [template engine](/docs/templates/legacy_json_templates/engine).
 	// Therefore, you may use user variables and template functions in this field.
 	// If not specified, this will default to `packer-import-{{timestamp}}`.
+	// The upload is skipped when an object with this name and the same
+	// SHA256 checksum already exists, such as when re-running a failed import.
 	ObjectName string `mapstructure:"space_object_name"`
+	// The URL of an image to import instead of uploading the artifact, either
+	// an `https://` URL, such as a vendor cloud image, or
+	// `spaces://<space>/<key>` for an existing object of a Space, which is
+	// imported through a presigned URL. `space_name` and
+	// `space_object_name` can not be set, and the Spaces credentials and
+	// region are only required for `spaces://` URLs. The source is never
+	// deleted.
+	ImageURL string `mapstructure:"image_url"`
 	// Whether we should skip removing the image file uploaded to Spaces after
 	// the import process has completed. "true" means that we should leave it in
 	// the Space, "false" means to clean it out. Defaults to `false`.
 	SkipClean bool `mapstructure:"skip_clean"`
 	// A list of tags to apply to the resulting imported image.
 	Tags []string `mapstructure:"image_tags"`
+	// The format to convert the image file to before uploading it, one of
+	// `raw`, `qcow2`, `vhdx`, `vdi`, or `vmdk`. Images in another format are
+	// converted with `qemu-img`, which must be installed. By default, the
+	// image file is uploaded as is.
+	ImageFormat string `mapstructure:"image_format"`
+	// The path of the `qemu-img` command used by `image_format`. Defaults to
+	// `qemu-img`.
+	QemuImgPath string `mapstructure:"qemu_img_path"`
+	// The compression of the image file before uploading it, `gzip` or
+	// `bzip2`. Compressing with `bzip2` requires the `bzip2` command.
+	// Already compressed files are uploaded as is. By default, the image file
+	// is not compressed.
+	ImageCompression string `mapstructure:"image_compression"`
 	// The name to be used for the resulting DigitalOcean custom image.
 	Name string `mapstructure:"image_name" required:"true"`
 	// The description to set for the resulting imported image.
@@ -83,6 +128,10 @@ type Config struct {
 	// as well as distributing the resulting image to additional regions. If not
 	// specified, this will default to 20.
 	Timeout time.Duration `mapstructure:"timeout"`
+	// The initial time to wait between two checks of the import and of the
+	// transfers to additional regions. The delay doubles after each check, up
+	// to 30 seconds. The default poll interval is "3s".
+	PollInterval time.Duration `mapstructure:"poll_interval"`
 
 	ctx interpolate.Context
 }
@@ -91,20 +140,10 @@ type PostProcessor struct {
 	config Config
 }
 
-type apiTokenSource struct {
-	AccessToken string
-}
-
 type logger struct {
 	logger *log.Logger
 }
 
-func (t *apiTokenSource) Token() (*oauth2.Token, error) {
-	return &oauth2.Token{
-		AccessToken: t.AccessToken,
-	}, nil
-}
-
 func (l logger) Log(args ...interface{}) {
 	l.logger.Println(args...)
 }
@@ -132,73 +171,68 @@ func (p *PostProcessor) Configure(raws ...interface{}) error {
 		p.config.SpacesSecret = os.Getenv("DIGITALOCEAN_SPACES_SECRET_KEY")
 	}
 
-	if p.config.APIToken == "" {
-		p.config.APIToken = os.Getenv("DIGITALOCEAN_TOKEN")
+	objectNameSet := p.config.ObjectName != ""
+	if !objectNameSet {
+		p.config.ObjectName = "packer-import-{{timestamp}}"
 	}
-	if p.config.APIToken == "" {
-		p.config.APIToken = os.Getenv("DIGITALOCEAN_ACCESS_TOKEN")
+
+	if p.config.Distribution == "" {
+		p.config.Distribution = "Unkown"
 	}
-	if p.config.APIToken == "" {
-		p.config.APIToken = os.Getenv("DIGITALOCEAN_API_TOKEN")
+
+	if p.config.Timeout == 0 {
+		p.config.Timeout = 20 * time.Minute
 	}
 
-	if p.config.HTTPRetryMax == nil {
-		p.config.HTTPRetryMax = godo.PtrTo(5)
-		if max := os.Getenv("DIGITALOCEAN_HTTP_RETRY_MAX"); max != "" {
-			maxInt, err := strconv.Atoi(max)
-			if err != nil {
-				return err
-			}
-			p.config.HTTPRetryMax = godo.PtrTo(maxInt)
-		}
+	if p.config.PollInterval == 0 {
+		p.config.PollInterval = docommon.DefaultPollInterval
 	}
-	if p.config.HTTPRetryWaitMax == nil {
-		p.config.HTTPRetryWaitMax = godo.PtrTo(30.0)
-		if waitMax := os.Getenv("DIGITALOCEAN_HTTP_RETRY_WAIT_MAX"); waitMax != "" {
-			waitMaxFloat, err := strconv.ParseFloat(waitMax, 64)
-			if err != nil {
-				return err
-			}
-			p.config.HTTPRetryWaitMax = godo.PtrTo(waitMaxFloat)
-		}
+
+	if p.config.SpacesACL == "" {
+		p.config.SpacesACL = s3.ObjectCannedACLPrivate
 	}
-	if p.config.HTTPRetryWaitMin == nil {
-		p.config.HTTPRetryWaitMin = godo.PtrTo(1.0)
-		if waitMin := os.Getenv("DIGITALOCEAN_HTTP_RETRY_WAIT_MIN"); waitMin != "" {
-			waitMinFloat, err := strconv.ParseFloat(waitMin, 64)
-			if err != nil {
-				return err
-			}
-			p.config.HTTPRetryWaitMin = godo.PtrTo(waitMinFloat)
-		}
+
+	if p.config.SpacesUploadPartSize == 0 {
+		p.config.SpacesUploadPartSize = s3manager.MinUploadPartSize / (1024 * 1024)
 	}
 
-	if p.config.ObjectName == "" {
-		p.config.ObjectName = "packer-import-{{timestamp}}"
+	if p.config.SpacesUploadConcurrency == 0 {
+		p.config.SpacesUploadConcurrency = s3manager.DefaultUploadConcurrency
 	}
 
-	if p.config.Distribution == "" {
-		p.config.Distribution = "Unkown"
+	if p.config.QemuImgPath == "" {
+		p.config.QemuImgPath = "qemu-img"
 	}
 
-	if p.config.Timeout == 0 {
-		p.config.Timeout = 20 * time.Minute
+	if p.config.SpacesEndpoint == "" && p.config.SpacesRegion != "" {
+		p.config.SpacesEndpoint = fmt.Sprintf("https://%s.digitaloceanspaces.com", p.config.SpacesRegion)
 	}
 
 	errs := new(packersdk.MultiError)
 
+	warns, es := p.config.AccessConfig.Prepare()
+	for _, w := range warns {
+		log.Printf("[WARN] %s", w)
+	}
+	if len(es) > 0 {
+		errs = packersdk.MultiErrorAppend(errs, es...)
+	}
+
 	if err = interpolate.Validate(p.config.ObjectName, &p.config.ctx); err != nil {
 		errs = packersdk.MultiErrorAppend(
 			errs, fmt.Errorf("Error parsing space_object_name template: %s", err))
 	}
 
 	requiredArgs := map[string]*string{
-		"api_token":     &p.config.APIToken,
-		"spaces_key":    &p.config.SpacesKey,
-		"spaces_secret": &p.config.SpacesSecret,
-		"spaces_region": &p.config.SpacesRegion,
-		"space_name":    &p.config.SpaceName,
-		"image_name":    &p.config.Name,
+		"image_name": &p.config.Name,
+	}
+	if p.config.ImageURL == "" || strings.HasPrefix(p.config.ImageURL, "spaces://") {
+		requiredArgs["spaces_key"] = &p.config.SpacesKey
+		requiredArgs["spaces_secret"] = &p.config.SpacesSecret
+		requiredArgs["spaces_region"] = &p.config.SpacesRegion
+	}
+	if p.config.ImageURL == "" {
+		requiredArgs["space_name"] = &p.config.SpaceName
 	}
 	for key, ptr := range requiredArgs {
 		if *ptr == "" {
@@ -207,6 +241,74 @@ func (p *PostProcessor) Configure(raws ...interface{}) error {
 		}
 	}
 
+	if p.config.ImageURL != "" {
+		u, err := url.Parse(p.config.ImageURL)
+		switch {
+		case err != nil || (u.Scheme != "https" && u.Scheme != "spaces"):
+			errs = packersdk.MultiErrorAppend(
+				errs, fmt.Errorf("image_url must be an https:// or spaces:// URL: %s", p.config.ImageURL))
+		case u.Scheme == "spaces" && (u.Host == "" || strings.Trim(u.Path, "/") == ""):
+			errs = packersdk.MultiErrorAppend(
+				errs, fmt.Errorf("image_url must be in the form spaces://<space>/<key>: %s", p.config.ImageURL))
+		}
+
+		if p.config.SpaceName != "" || objectNameSet {
+			errs = packersdk.MultiErrorAppend(
+				errs, errors.New("space_name and space_object_name can not be used with image_url"))
+		}
+	}
+
+	if p.config.SpacesEndpoint != "" {
+		if u, err := url.Parse(p.config.SpacesEndpoint); err != nil || u.Scheme == "" || u.Host == "" {
+			errs = packersdk.MultiErrorAppend(
+				errs, fmt.Errorf("spaces_endpoint must be an absolute URL: %s", p.config.SpacesEndpoint))
+		}
+	}
+
+	if p.config.SpacesACL != s3.ObjectCannedACLPrivate && p.config.SpacesACL != s3.ObjectCannedACLPublicRead {
+		errs = packersdk.MultiErrorAppend(
+			errs, fmt.Errorf("spaces_acl must be %s or %s", s3.ObjectCannedACLPrivate, s3.ObjectCannedACLPublicRead))
+	}
+
+	if p.config.SpacesUploadPartSize*1024*1024 < s3manager.MinUploadPartSize {
+		errs = packersdk.MultiErrorAppend(
+			errs, fmt.Errorf("spaces_upload_part_size must be at least %d", s3manager.MinUploadPartSize/(1024*1024)))
+	}
+
+	if p.config.SpacesUploadConcurrency < 1 {
+		errs = packersdk.MultiErrorAppend(
+			errs, errors.New("spaces_upload_concurrency must be positive"))
+	}
+
+	if p.config.ImageFormat != "" && !contains(imageFormats, p.config.ImageFormat) {
+		errs = packersdk.MultiErrorAppend(
+			errs, fmt.Errorf("image_format must be one of %s", strings.Join(imageFormats, ", ")))
+	}
+
+	if p.config.ImageCompression != "" && !contains(imageCompressions, p.config.ImageCompression) {
+		errs = packersdk.MultiErrorAppend(
+			errs, fmt.Errorf("image_compression must be one of %s", strings.Join(imageCompressions, ", ")))
+	}
+
+	if p.config.ImageURL != "" && (p.config.ImageFormat != "" || p.config.ImageCompression != "") {
+		errs = packersdk.MultiErrorAppend(
+			errs, errors.New("image_format and image_compression can not be used with image_url"))
+	}
+
+	if p.config.ImageFormat != "" {
+		if _, err := exec.LookPath(p.config.QemuImgPath); err != nil {
+			errs = packersdk.MultiErrorAppend(
+				errs, fmt.Errorf("image_format requires qemu-img, set qemu_img_path to its path: %s", err))
+		}
+	}
+
+	if p.config.ImageCompression == "bzip2" {
+		if _, err := exec.LookPath("bzip2"); err != nil {
+			errs = packersdk.MultiErrorAppend(
+				errs, fmt.Errorf("image_compression bzip2 requires the bzip2 command: %s", err))
+		}
+	}
+
 	if len(p.config.ImageRegions) == 0 {
 		errs = packersdk.MultiErrorAppend(
 			errs, fmt.Errorf("image_regions must be set"))
@@ -216,7 +318,7 @@ func (p *PostProcessor) Configure(raws ...interface{}) error {
 		return errs
 	}
 
-	packersdk.LogSecretFilter.Set(p.config.SpacesKey, p.config.SpacesSecret, p.config.APIToken)
+	packersdk.LogSecretFilter.Set(p.config.SpacesKey, p.config.SpacesSecret)
 	log.Println(p.config)
 	return nil
 }
@@ -231,68 +333,82 @@ func (p *PostProcessor) PostProcess(ctx context.Context, ui packersdk.Ui, artifa
 	}
 	p.config.ctx.Data = generatedData
 
-	p.config.ObjectName, err = interpolate.Render(p.config.ObjectName, &p.config.ctx)
-	if err != nil {
-		return nil, false, false, fmt.Errorf("Error rendering space_object_name template: %s", err)
+	var sess *session.Session
+	if p.config.ImageURL == "" || strings.HasPrefix(p.config.ImageURL, "spaces://") {
+		sess, err = p.spacesSession()
+		if err != nil {
+			return nil, false, false, err
+		}
 	}
-	log.Printf("Rendered space_object_name as %s", p.config.ObjectName)
 
-	log.Println("Looking for image in artifact")
-	source, err := extractImageArtifact(artifact.Files())
-	if err != nil {
-		return nil, false, false, fmt.Errorf("Image file not found")
-	}
+	var imageURL string
+	switch {
+	case strings.HasPrefix(p.config.ImageURL, "spaces://"):
+		u, _ := url.Parse(p.config.ImageURL)
+		p.config.SpaceName = u.Host
+		p.config.ObjectName = strings.TrimPrefix(u.Path, "/")
 
-	spacesCreds := credentials.NewStaticCredentials(p.config.SpacesKey, p.config.SpacesSecret, "")
-	spacesEndpoint := fmt.Sprintf("https://%s.digitaloceanspaces.com", p.config.SpacesRegion)
-	spacesConfig := &aws.Config{
-		Credentials: spacesCreds,
-		Endpoint:    aws.String(spacesEndpoint),
-		Region:      aws.String(p.config.SpacesRegion),
-		LogLevel:    aws.LogLevel(aws.LogDebugWithSigning),
-		Logger: &logger{
-			logger: log.New(os.Stderr, "", log.LstdFlags),
-		},
-	}
-	sess, err := session.NewSession(spacesConfig)
-	if err != nil {
-		return nil, false, false, err
-	}
+		// The ACL of an existing object is unknown, so it is always presigned
+		imageURL, err = presignedObjectURL(p, sess)
+		if err != nil {
+			return nil, false, false, err
+		}
+	case p.config.ImageURL != "":
+		imageURL = p.config.ImageURL
+	default:
+		p.config.ObjectName, err = interpolate.Render(p.config.ObjectName, &p.config.ctx)
+		if err != nil {
+			return nil, false, false, fmt.Errorf("Error rendering space_object_name template: %s", err)
+		}
+		log.Printf("Rendered space_object_name as %s", p.config.ObjectName)
 
-	ui.Message(fmt.Sprintf("Uploading %s to spaces://%s/%s", source, p.config.SpaceName, p.config.ObjectName))
-	err = uploadImageToSpaces(source, p, sess)
-	if err != nil {
-		return nil, false, false, err
-	}
-	ui.Message(fmt.Sprintf("Completed upload of %s to spaces://%s/%s", source, p.config.SpaceName, p.config.ObjectName))
+		log.Println("Looking for image in artifact")
+		source, err := extractImageArtifact(artifact.Files())
+		if err != nil {
+			return nil, false, false, fmt.Errorf("Image file not found")
+		}
 
-	ua := useragent.String(version.PluginVersion.FormattedVersion())
-	opts := []godo.ClientOpt{godo.SetUserAgent(ua)}
+		dir, err := os.MkdirTemp("", "packer-digitalocean-import")
+		if err != nil {
+			return nil, false, false, err
+		}
+		defer os.RemoveAll(dir)
 
-	if *p.config.HTTPRetryMax > 0 {
-		opts = append(opts, godo.WithRetryAndBackoffs(godo.RetryConfig{
-			RetryMax:     *p.config.HTTPRetryMax,
-			RetryWaitMin: p.config.HTTPRetryWaitMin,
-			RetryWaitMax: p.config.HTTPRetryWaitMax,
-			Logger:       log.Default(),
-		}))
+		source, err = prepareImage(ctx, ui, p, source, dir)
+		if err != nil {
+			return nil, false, false, err
+		}
+
+		ui.Message(fmt.Sprintf("Uploading %s to spaces://%s/%s", source, p.config.SpaceName, p.config.ObjectName))
+		uploaded, err := uploadImageToSpaces(ui, source, p, sess)
+		if err != nil {
+			return nil, false, false, err
+		}
+		if uploaded {
+			ui.Message(fmt.Sprintf("Completed upload of %s to spaces://%s/%s", source, p.config.SpaceName, p.config.ObjectName))
+		} else {
+			ui.Message(fmt.Sprintf("Skipped upload, spaces://%s/%s already has the same checksum", p.config.SpaceName, p.config.ObjectName))
+		}
+
+		imageURL, err = importURL(p, sess)
+		if err != nil {
+			return nil, false, false, err
+		}
 	}
 
-	client, err := godo.New(oauth2.NewClient(context.TODO(), &apiTokenSource{
-		AccessToken: p.config.APIToken,
-	}), opts...)
+	client, err := p.config.Client()
 	if err != nil {
 		return nil, false, false, fmt.Errorf("DigitalOcean: could not create client, %s", err)
 	}
 
-	ui.Message(fmt.Sprintf("Started import of spaces://%s/%s", p.config.SpaceName, p.config.ObjectName))
-	image, err := importImageFromSpaces(p, client)
+	ui.Message(fmt.Sprintf("Started import of %s", p.importSource()))
+	image, err := importImage(p, client, imageURL)
 	if err != nil {
 		return nil, false, false, err
 	}
 
 	ui.Message(fmt.Sprintf("Waiting for import of image %s to complete (may take a while)", p.config.Name))
-	err = waitUntilImageAvailable(client, image.ID, p.config.Timeout)
+	err = waitUntilImageAvailable(ctx, client, image.ID, p.config.PollInterval, p.config.Timeout)
 	if err != nil {
 		return nil, false, false, fmt.Errorf("Import of image %s failed with error: %s", p.config.Name, err)
 	}
@@ -306,7 +422,7 @@ func (p *PostProcessor) PostProcess(ctx context.Context, ui packersdk.Ui, artifa
 		regions = regions[:len(regions)-1]
 
 		ui.Message(fmt.Sprintf("Distributing image %s to additional regions: %v", p.config.Name, regions))
-		err = distributeImageToRegions(client, image.ID, regions, p.config.Timeout)
+		err = distributeImageToRegions(ctx, client, image.ID, regions, p.config.PollInterval, p.config.Timeout)
 		if err != nil {
 			return nil, false, false, err
 		}
@@ -320,7 +436,8 @@ func (p *PostProcessor) PostProcess(ctx context.Context, ui packersdk.Ui, artifa
 		Client:       client,
 	}
 
-	if !p.config.SkipClean {
+	// Only objects uploaded by the post-processor are removed
+	if !p.config.SkipClean && p.config.ImageURL == "" {
 		ui.Message(fmt.Sprintf("Deleting import source spaces://%s/%s", p.config.SpaceName, p.config.ObjectName))
 		err = deleteImageFromSpaces(p, sess)
 		if err != nil {
@@ -354,35 +471,154 @@ func extractImageArtifact(artifacts []string) (string, error) {
 	return "", fmt.Errorf("no valid image file found")
 }
 
-func uploadImageToSpaces(source string, p *PostProcessor, s *session.Session) (err error) {
+// uploadImageToSpaces uploads the image file with its SHA256 checksum in the
+// object metadata, reporting progress to the Ui, and verifies the checksum
+// recorded for the uploaded object, as well as the ETag Spaces computed for
+// it. The upload is skipped, returning false, when the object already exists
+// with the same checksum.
+func uploadImageToSpaces(ui packersdk.Ui, source string, p *PostProcessor, s *session.Session) (bool, error) {
 	file, err := os.Open(source)
 	if err != nil {
-		return fmt.Errorf("Failed to open %s: %s", source, err)
+		return false, fmt.Errorf("Failed to open %s: %s", source, err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return false, fmt.Errorf("Failed to stat %s: %s", source, err)
+	}
+
+	// The uploader can not size the parts of a stream, so they are sized
+	// here to stay within the maximum number of parts
+	partSize := p.config.SpacesUploadPartSize * 1024 * 1024
+	if min := info.Size()/s3manager.MaxUploadParts + 1; partSize < min {
+		partSize = min
+	}
+
+	ui.Message(fmt.Sprintf("Computing the checksum of %s", source))
+	sum, etag, err := fileChecksums(file, info.Size(), partSize)
+	if err != nil {
+		return false, fmt.Errorf("Failed to compute the checksum of %s: %s", source, err)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return false, fmt.Errorf("Failed to read %s: %s", source, err)
+	}
+
+	existing, _, err := objectChecksums(p, s)
+	if err != nil {
+		return false, err
+	}
+	if existing == sum {
+		log.Printf("spaces://%s/%s already has checksum %s", p.config.SpaceName, p.config.ObjectName, sum)
+		return false, nil
 	}
 
-	uploader := s3manager.NewUploader(s)
+	body := ui.TrackProgress(filepath.Base(source), 0, info.Size(), file)
+	defer body.Close()
+
+	uploader := s3manager.NewUploader(s, func(u *s3manager.Uploader) {
+		u.PartSize = partSize
+		u.Concurrency = p.config.SpacesUploadConcurrency
+	})
 	_, err = uploader.Upload(&s3manager.UploadInput{
-		Body:   file,
+		// The body is read as a stream so that the parts are always cut
+		// the way fileChecksums expects, even if the Ui returns the file as
+		// is
+		Body:     struct{ io.Reader }{body},
+		Bucket:   &p.config.SpaceName,
+		Key:      &p.config.ObjectName,
+		ACL:      aws.String(p.config.SpacesACL),
+		Metadata: map[string]*string{checksumMetadataKey: aws.String(sum)},
+	})
+	if err != nil {
+		return false, fmt.Errorf("Failed to upload %s: %s", source, err)
+	}
+
+	uploadedSum, uploadedETag, err := objectChecksums(p, s)
+	if err != nil {
+		return false, err
+	}
+	if uploadedSum != sum {
+		return false, fmt.Errorf("Checksum mismatch for spaces://%s/%s: expected SHA256 %s, got %q",
+			p.config.SpaceName, p.config.ObjectName, sum, uploadedSum)
+	}
+	if uploadedETag != etag {
+		return false, fmt.Errorf("Checksum mismatch for spaces://%s/%s: expected ETag %s, got %q",
+			p.config.SpaceName, p.config.ObjectName, etag, uploadedETag)
+	}
+
+	return true, nil
+}
+
+// objectChecksums returns the SHA256 checksum recorded in the metadata of the
+// object and its ETag, or empty strings if the object does not exist.
+func objectChecksums(p *PostProcessor, s *session.Session) (sum string, etag string, err error) {
+	head, err := s3.New(s).HeadObject(&s3.HeadObjectInput{
 		Bucket: &p.config.SpaceName,
 		Key:    &p.config.ObjectName,
-		ACL:    aws.String("public-read"),
 	})
 	if err != nil {
-		return fmt.Errorf("Failed to upload %s: %s", source, err)
+		if reqErr, ok := err.(awserr.RequestFailure); ok && reqErr.StatusCode() == http.StatusNotFound {
+			return "", "", nil
+		}
+		return "", "", fmt.Errorf("Failed to look up spaces://%s/%s: %s", p.config.SpaceName, p.config.ObjectName, err)
+	}
+
+	for k, v := range head.Metadata {
+		if strings.EqualFold(k, checksumMetadataKey) && v != nil {
+			sum = *v
+		}
 	}
 
-	file.Close()
+	return sum, strings.Trim(aws.StringValue(head.ETag), `"`), nil
+}
 
-	return nil
+// fileChecksums reads r once and returns the hex encoded SHA256 checksum of
+// its content, along with the ETag Spaces computes for it when it is
+// uploaded in parts of partSize.
+func fileChecksums(r io.Reader, size, partSize int64) (sum string, etag string, err error) {
+	h := sha256.New()
+	etag, err = fileETag(io.TeeReader(r, h), size, partSize)
+	if err != nil {
+		return "", "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), etag, nil
 }
 
-func importImageFromSpaces(p *PostProcessor, client *godo.Client) (image *godo.Image, err error) {
-	log.Printf("Importing custom image from spaces://%s/%s", p.config.SpaceName, p.config.ObjectName)
+// fileETag returns the ETag Spaces computes for the content of r when it is
+// uploaded in parts of partSize: the MD5 checksum of a single part upload,
+// or the MD5 checksum of the MD5 checksums of the parts followed by the
+// number of parts.
+func fileETag(r io.Reader, size, partSize int64) (string, error) {
+	if size < partSize {
+		h := md5.New()
+		if _, err := io.Copy(h, r); err != nil {
+			return "", err
+		}
+		return hex.EncodeToString(h.Sum(nil)), nil
+	}
+
+	sums := md5.New()
+	parts := 0
+	for ; size > 0; size -= partSize {
+		h := md5.New()
+		if _, err := io.CopyN(h, r, partSize); err != nil && err != io.EOF {
+			return "", err
+		}
+		sums.Write(h.Sum(nil))
+		parts++
+	}
+
+	return fmt.Sprintf("%s-%d", hex.EncodeToString(sums.Sum(nil)), parts), nil
+}
+
+func importImage(p *PostProcessor, client *godo.Client, imageURL string) (image *godo.Image, err error) {
+	log.Printf("Importing custom image from %s", p.importSource())
 
-	url := fmt.Sprintf("https://%s.%s.digitaloceanspaces.com/%s", p.config.SpaceName, p.config.SpacesRegion, p.config.ObjectName)
 	createRequest := &godo.CustomImageCreateRequest{
 		Name:         p.config.Name,
-		Url:          url,
+		Url:          imageURL,
 		Region:       p.config.ImageRegions[0],
 		Distribution: p.config.Distribution,
 		Description:  p.config.Description,
@@ -391,72 +627,119 @@ func importImageFromSpaces(p *PostProcessor, client *godo.Client) (image *godo.I
 
 	image, _, err = client.Images.Create(context.TODO(), createRequest)
 	if err != nil {
-		return image, fmt.Errorf("Failed to import from spaces://%s/%s: %s", p.config.SpaceName, p.config.ObjectName, err)
+		return image, fmt.Errorf("Failed to import from %s: %s", p.importSource(), err)
 	}
 
 	return image, nil
 }
 
-func waitUntilImageAvailable(client *godo.Client, imageId int, timeout time.Duration) (err error) {
-	done := make(chan struct{})
-	defer close(done)
+// importSource describes where the image is imported from, without the
+// signature of presigned URLs.
+func (p *PostProcessor) importSource() string {
+	if p.config.ImageURL != "" {
+		return p.config.ImageURL
+	}
 
-	result := make(chan error, 1)
-	go func() {
-		attempts := 0
-		for {
-			attempts += 1
+	return fmt.Sprintf("spaces://%s/%s", p.config.SpaceName, p.config.ObjectName)
+}
 
-			log.Printf("Waiting for image to become available... (attempt: %d)", attempts)
-			image, _, err := client.Images.GetByID(context.TODO(), imageId)
-			if err != nil {
-				result <- err
-				return
-			}
+// spacesSession returns a session for the Spaces API.
+func (p *PostProcessor) spacesSession() (*session.Session, error) {
+	spacesCreds := credentials.NewStaticCredentials(p.config.SpacesKey, p.config.SpacesSecret, "")
+	spacesConfig := &aws.Config{
+		Credentials:      spacesCreds,
+		Endpoint:         aws.String(p.config.SpacesEndpoint),
+		Region:           aws.String(p.config.SpacesRegion),
+		S3ForcePathStyle: aws.Bool(p.config.SpacesForcePathStyle),
+		LogLevel:         aws.LogLevel(aws.LogDebugWithSigning),
+		Logger: &logger{
+			logger: log.New(os.Stderr, "", log.LstdFlags),
+		},
+	}
 
-			if image.Status == "available" {
-				result <- nil
-				return
-			}
+	return session.NewSession(spacesConfig)
+}
 
-			if image.ErrorMessage != "" {
-				result <- fmt.Errorf("%v", image.ErrorMessage)
-				return
-			}
+// importURL returns the URL DigitalOcean downloads the image from. Private
+// objects are shared through a presigned URL expiring after the import
+// timeout.
+func importURL(p *PostProcessor, s *session.Session) (string, error) {
+	if p.config.SpacesACL == s3.ObjectCannedACLPublicRead {
+		return objectURL(p.config.SpacesEndpoint, p.config.SpaceName, p.config.ObjectName, p.config.SpacesForcePathStyle)
+	}
 
-			time.Sleep(3 * time.Second)
+	return presignedObjectURL(p, s)
+}
 
-			select {
-			case <-done:
-				return
-			default:
-			}
-		}
-	}()
+// presignedObjectURL returns a presigned GET URL of the object, valid for
+// the import timeout.
+func presignedObjectURL(p *PostProcessor, s *session.Session) (string, error) {
+	req, _ := s3.New(s).GetObjectRequest(&s3.GetObjectInput{
+		Bucket: &p.config.SpaceName,
+		Key:    &p.config.ObjectName,
+	})
+	presigned, err := req.Presign(p.config.Timeout)
+	if err != nil {
+		return "", fmt.Errorf("Failed to presign spaces://%s/%s: %s", p.config.SpaceName, p.config.ObjectName, err)
+	}
 
-	log.Printf("Waiting for up to %d seconds for image to become available", timeout/time.Second)
-	select {
-	case err := <-result:
-		return err
-	case <-time.After(timeout):
-		err := fmt.Errorf("Timeout while waiting to for action to become available")
-		return err
+	return presigned, nil
+}
+
+// objectURL returns the URL of the object in the Space, with the bucket in
+// the host name or, with path-style addressing, in the path.
+func objectURL(endpoint, bucket, key string, pathStyle bool) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("Failed to parse spaces_endpoint: %s", err)
+	}
+
+	if pathStyle {
+		u.Path = path.Join("/", u.Path, bucket, key)
+	} else {
+		u.Host = bucket + "." + u.Host
+		u.Path = path.Join("/", u.Path, key)
 	}
+
+	return u.String(), nil
+}
+
+func waitUntilImageAvailable(ctx context.Context, client *godo.Client, imageId int, pollInterval, timeout time.Duration) (err error) {
+	return docommon.WaitFor(ctx, docommon.WaitOptions{
+		Description:  "image to become available",
+		PollInterval: pollInterval,
+		Timeout:      timeout,
+	}, func(ctx context.Context) (bool, error) {
+		image, _, err := client.Images.GetByID(ctx, imageId)
+		if err != nil {
+			return false, err
+		}
+
+		if image.Status == "available" {
+			return true, nil
+		}
+
+		if image.ErrorMessage != "" {
+			return false, fmt.Errorf("%v", image.ErrorMessage)
+		}
+
+		return false, nil
+	})
 }
 
-func distributeImageToRegions(client *godo.Client, imageId int, regions []string, timeout time.Duration) (err error) {
+func distributeImageToRegions(ctx context.Context, client *godo.Client, imageId int, regions []string, pollInterval, timeout time.Duration) (err error) {
 	for _, region := range regions {
 		transferRequest := &godo.ActionRequest{
 			"type":   "transfer",
 			"region": region,
 		}
 		log.Printf("Transferring image to %s", region)
-		action, _, err := client.ImageActions.Transfer(context.TODO(), imageId, transferRequest)
+		action, _, err := client.ImageActions.Transfer(ctx, imageId, transferRequest)
 		if err != nil {
 			return fmt.Errorf("Error transferring image: %s", err)
 		}
 
-		if err := digitalocean.WaitForImageState(godo.ActionCompleted, imageId, action.ID, client, timeout); err != nil {
+		if err := digitalocean.WaitForImageState(ctx, godo.ActionCompleted, imageId, action.ID, client, pollInterval, timeout); err != nil {
 			if err != nil {
 				return fmt.Errorf("Error transferring image: %s", err)
 			}
@@ -478,3 +761,12 @@ func deleteImageFromSpaces(p *PostProcessor, s *session.Session) (err error) {
 
 	return nil
 }
+
+func contains(list []string, term string) bool {
+	for _, t := range list {
+		if t == term {
+			return true
+		}
+	}
+	return false
+}
@@ -10,30 +10,41 @@ import (
 // FlatConfig is an auto-generated flat version of Config.
 // Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
 type FlatConfig struct {
-	PackerBuildName     *string           `mapstructure:"packer_build_name" cty:"packer_build_name" hcl:"packer_build_name"`
-	PackerBuilderType   *string           `mapstructure:"packer_builder_type" cty:"packer_builder_type" hcl:"packer_builder_type"`
-	PackerCoreVersion   *string           `mapstructure:"packer_core_version" cty:"packer_core_version" hcl:"packer_core_version"`
-	PackerDebug         *bool             `mapstructure:"packer_debug" cty:"packer_debug" hcl:"packer_debug"`
-	PackerForce         *bool             `mapstructure:"packer_force" cty:"packer_force" hcl:"packer_force"`
-	PackerOnError       *string           `mapstructure:"packer_on_error" cty:"packer_on_error" hcl:"packer_on_error"`
-	PackerUserVars      map[string]string `mapstructure:"packer_user_variables" cty:"packer_user_variables" hcl:"packer_user_variables"`
-	PackerSensitiveVars []string          `mapstructure:"packer_sensitive_variables" cty:"packer_sensitive_variables" hcl:"packer_sensitive_variables"`
-	APIToken            *string           `mapstructure:"api_token" required:"true" cty:"api_token" hcl:"api_token"`
-	SpacesKey           *string           `mapstructure:"spaces_key" required:"true" cty:"spaces_key" hcl:"spaces_key"`
-	SpacesSecret        *string           `mapstructure:"spaces_secret" required:"true" cty:"spaces_secret" hcl:"spaces_secret"`
-	HTTPRetryMax        *int              `mapstructure:"http_retry_max" required:"false" cty:"http_retry_max" hcl:"http_retry_max"`
-	HTTPRetryWaitMax    *float64          `mapstructure:"http_retry_wait_max" required:"false" cty:"http_retry_wait_max" hcl:"http_retry_wait_max"`
-	HTTPRetryWaitMin    *float64          `mapstructure:"http_retry_wait_min" required:"false" cty:"http_retry_wait_min" hcl:"http_retry_wait_min"`
-	SpacesRegion        *string           `mapstructure:"spaces_region" required:"true" cty:"spaces_region" hcl:"spaces_region"`
-	SpaceName           *string           `mapstructure:"space_name" required:"true" cty:"space_name" hcl:"space_name"`
-	ObjectName          *string           `mapstructure:"space_object_name" cty:"space_object_name" hcl:"space_object_name"`
-	SkipClean           *bool             `mapstructure:"skip_clean" cty:"skip_clean" hcl:"skip_clean"`
-	Tags                []string          `mapstructure:"image_tags" cty:"image_tags" hcl:"image_tags"`
-	Name                *string           `mapstructure:"image_name" required:"true" cty:"image_name" hcl:"image_name"`
-	Description         *string           `mapstructure:"image_description" cty:"image_description" hcl:"image_description"`
-	Distribution        *string           `mapstructure:"image_distribution" cty:"image_distribution" hcl:"image_distribution"`
-	ImageRegions        []string          `mapstructure:"image_regions" required:"true" cty:"image_regions" hcl:"image_regions"`
-	Timeout             *string           `mapstructure:"timeout" cty:"timeout" hcl:"timeout"`
+	PackerBuildName         *string           `mapstructure:"packer_build_name" cty:"packer_build_name" hcl:"packer_build_name"`
+	PackerBuilderType       *string           `mapstructure:"packer_builder_type" cty:"packer_builder_type" hcl:"packer_builder_type"`
+	PackerCoreVersion       *string           `mapstructure:"packer_core_version" cty:"packer_core_version" hcl:"packer_core_version"`
+	PackerDebug             *bool             `mapstructure:"packer_debug" cty:"packer_debug" hcl:"packer_debug"`
+	PackerForce             *bool             `mapstructure:"packer_force" cty:"packer_force" hcl:"packer_force"`
+	PackerOnError           *string           `mapstructure:"packer_on_error" cty:"packer_on_error" hcl:"packer_on_error"`
+	PackerUserVars          map[string]string `mapstructure:"packer_user_variables" cty:"packer_user_variables" hcl:"packer_user_variables"`
+	PackerSensitiveVars     []string          `mapstructure:"packer_sensitive_variables" cty:"packer_sensitive_variables" hcl:"packer_sensitive_variables"`
+	APIToken                *string           `mapstructure:"api_token" required:"true" cty:"api_token" hcl:"api_token"`
+	APIURL                  *string           `mapstructure:"api_url" required:"false" cty:"api_url" hcl:"api_url"`
+	HTTPRetryMax            *int              `mapstructure:"http_retry_max" required:"false" cty:"http_retry_max" hcl:"http_retry_max"`
+	HTTPRetryWaitMax        *float64          `mapstructure:"http_retry_wait_max" required:"false" cty:"http_retry_wait_max" hcl:"http_retry_wait_max"`
+	HTTPRetryWaitMin        *float64          `mapstructure:"http_retry_wait_min" required:"false" cty:"http_retry_wait_min" hcl:"http_retry_wait_min"`
+	SpacesKey               *string           `mapstructure:"spaces_key" required:"true" cty:"spaces_key" hcl:"spaces_key"`
+	SpacesSecret            *string           `mapstructure:"spaces_secret" required:"true" cty:"spaces_secret" hcl:"spaces_secret"`
+	SpacesRegion            *string           `mapstructure:"spaces_region" required:"true" cty:"spaces_region" hcl:"spaces_region"`
+	SpaceName               *string           `mapstructure:"space_name" required:"true" cty:"space_name" hcl:"space_name"`
+	SpacesEndpoint          *string           `mapstructure:"spaces_endpoint" cty:"spaces_endpoint" hcl:"spaces_endpoint"`
+	SpacesForcePathStyle    *bool             `mapstructure:"spaces_force_path_style" cty:"spaces_force_path_style" hcl:"spaces_force_path_style"`
+	SpacesACL               *string           `mapstructure:"spaces_acl" cty:"spaces_acl" hcl:"spaces_acl"`
+	SpacesUploadPartSize    *int64            `mapstructure:"spaces_upload_part_size" cty:"spaces_upload_part_size" hcl:"spaces_upload_part_size"`
+	SpacesUploadConcurrency *int              `mapstructure:"spaces_upload_concurrency" cty:"spaces_upload_concurrency" hcl:"spaces_upload_concurrency"`
+	ObjectName              *string           `mapstructure:"space_object_name" cty:"space_object_name" hcl:"space_object_name"`
+	ImageURL                *string           `mapstructure:"image_url" cty:"image_url" hcl:"image_url"`
+	SkipClean               *bool             `mapstructure:"skip_clean" cty:"skip_clean" hcl:"skip_clean"`
+	Tags                    []string          `mapstructure:"image_tags" cty:"image_tags" hcl:"image_tags"`
+	ImageFormat             *string           `mapstructure:"image_format" cty:"image_format" hcl:"image_format"`
+	QemuImgPath             *string           `mapstructure:"qemu_img_path" cty:"qemu_img_path" hcl:"qemu_img_path"`
+	ImageCompression        *string           `mapstructure:"image_compression" cty:"image_compression" hcl:"image_compression"`
+	Name                    *string           `mapstructure:"image_name" required:"true" cty:"image_name" hcl:"image_name"`
+	Description             *string           `mapstructure:"image_description" cty:"image_description" hcl:"image_description"`
+	Distribution            *string           `mapstructure:"image_distribution" cty:"image_distribution" hcl:"image_distribution"`
+	ImageRegions            []string          `mapstructure:"image_regions" required:"true" cty:"image_regions" hcl:"image_regions"`
+	Timeout                 *string           `mapstructure:"timeout" cty:"timeout" hcl:"timeout"`
+	PollInterval            *string           `mapstructure:"poll_interval" cty:"poll_interval" hcl:"poll_interval"`
 }
 
 // FlatMapstructure returns a new FlatConfig.
@@ -57,21 +68,32 @@ func (*FlatConfig) HCL2Spec() map[string]hcldec.Spec {
 		"packer_user_variables":      &hcldec.AttrSpec{Name: "packer_user_variables", Type: cty.Map(cty.String), Required: false},
 		"packer_sensitive_variables": &hcldec.AttrSpec{Name: "packer_sensitive_variables", Type: cty.List(cty.String), Required: false},
 		"api_token":                  &hcldec.AttrSpec{Name: "api_token", Type: cty.String, Required: false},
-		"spaces_key":                 &hcldec.AttrSpec{Name: "spaces_key", Type: cty.String, Required: false},
-		"spaces_secret":              &hcldec.AttrSpec{Name: "spaces_secret", Type: cty.String, Required: false},
+		"api_url":                    &hcldec.AttrSpec{Name: "api_url", Type: cty.String, Required: false},
 		"http_retry_max":             &hcldec.AttrSpec{Name: "http_retry_max", Type: cty.Number, Required: false},
 		"http_retry_wait_max":        &hcldec.AttrSpec{Name: "http_retry_wait_max", Type: cty.Number, Required: false},
 		"http_retry_wait_min":        &hcldec.AttrSpec{Name: "http_retry_wait_min", Type: cty.Number, Required: false},
+		"spaces_key":                 &hcldec.AttrSpec{Name: "spaces_key", Type: cty.String, Required: false},
+		"spaces_secret":              &hcldec.AttrSpec{Name: "spaces_secret", Type: cty.String, Required: false},
 		"spaces_region":              &hcldec.AttrSpec{Name: "spaces_region", Type: cty.String, Required: false},
 		"space_name":                 &hcldec.AttrSpec{Name: "space_name", Type: cty.String, Required: false},
+		"spaces_endpoint":            &hcldec.AttrSpec{Name: "spaces_endpoint", Type: cty.String, Required: false},
+		"spaces_force_path_style":    &hcldec.AttrSpec{Name: "spaces_force_path_style", Type: cty.Bool, Required: false},
+		"spaces_acl":                 &hcldec.AttrSpec{Name: "spaces_acl", Type: cty.String, Required: false},
+		"spaces_upload_part_size":    &hcldec.AttrSpec{Name: "spaces_upload_part_size", Type: cty.Number, Required: false},
+		"spaces_upload_concurrency":  &hcldec.AttrSpec{Name: "spaces_upload_concurrency", Type: cty.Number, Required: false},
 		"space_object_name":          &hcldec.AttrSpec{Name: "space_object_name", Type: cty.String, Required: false},
+		"image_url":                  &hcldec.AttrSpec{Name: "image_url", Type: cty.String, Required: false},
 		"skip_clean":                 &hcldec.AttrSpec{Name: "skip_clean", Type: cty.Bool, Required: false},
 		"image_tags":                 &hcldec.AttrSpec{Name: "image_tags", Type: cty.List(cty.String), Required: false},
+		"image_format":               &hcldec.AttrSpec{Name: "image_format", Type: cty.String, Required: false},
+		"qemu_img_path":              &hcldec.AttrSpec{Name: "qemu_img_path", Type: cty.String, Required: false},
+		"image_compression":          &hcldec.AttrSpec{Name: "image_compression", Type: cty.String, Required: false},
 		"image_name":                 &hcldec.AttrSpec{Name: "image_name", Type: cty.String, Required: false},
 		"image_description":          &hcldec.AttrSpec{Name: "image_description", Type: cty.String, Required: false},
 		"image_distribution":         &hcldec.AttrSpec{Name: "image_distribution", Type: cty.String, Required: false},
 		"image_regions":              &hcldec.AttrSpec{Name: "image_regions", Type: cty.List(cty.String), Required: false},
 		"timeout":                    &hcldec.AttrSpec{Name: "timeout", Type: cty.String, Required: false},
+		"poll_interval":              &hcldec.AttrSpec{Name: "poll_interval", Type: cty.String, Required: false},
 	}
 	return s
 }
@@ -1,9 +1,28 @@
 package digitaloceanimport
 
 import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	docommon "github.com/digitalocean/packer-plugin-digitalocean/common"
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/stretchr/testify/require"
 )
 
 func TestPostProcessor_ImplementsPostProcessor(t *testing.T) {
@@ -37,3 +56,554 @@ func TestPostProcessor_ImageArtifactExtraction(t *testing.T) {
 		}
 	}
 }
+
+func TestObjectURL(t *testing.T) {
+	tests := []struct {
+		name      string
+		endpoint  string
+		pathStyle bool
+		expected  string
+	}{
+		{
+			name:     "virtual-hosted",
+			endpoint: "https://nyc3.digitaloceanspaces.com",
+			expected: "https://bucket.nyc3.digitaloceanspaces.com/images/disk.raw",
+		},
+		{
+			name:      "path-style",
+			endpoint:  "http://127.0.0.1:9000",
+			pathStyle: true,
+			expected:  "http://127.0.0.1:9000/bucket/images/disk.raw",
+		},
+		{
+			name:      "path-style with a base path",
+			endpoint:  "https://storage.example.com/s3/",
+			pathStyle: true,
+			expected:  "https://storage.example.com/s3/bucket/images/disk.raw",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := objectURL(tt.endpoint, "bucket", "images/disk.raw", tt.pathStyle)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, u)
+		})
+	}
+}
+
+func testConfig() map[string]interface{} {
+	return map[string]interface{}{
+		"api_token":         "token",
+		"spaces_key":        "key",
+		"spaces_secret":     "secret",
+		"spaces_region":     "nyc3",
+		"space_name":        "bucket",
+		"space_object_name": "image.raw",
+		"image_name":        "imported",
+		"image_regions":     []string{"nyc3"},
+	}
+}
+
+func TestPostProcessor_Configure_SpacesEndpoint(t *testing.T) {
+	var p PostProcessor
+	require.NoError(t, p.Configure(testConfig()))
+	require.Equal(t, "https://nyc3.digitaloceanspaces.com", p.config.SpacesEndpoint)
+
+	config := testConfig()
+	config["spaces_endpoint"] = "localhost:9000"
+	p = PostProcessor{}
+	require.Error(t, p.Configure(config))
+}
+
+// fakeSpaces is an S3-compatible server with path-style addressing, keeping
+// objects in memory.
+type fakeSpaces struct {
+	sync.Mutex
+	objects  map[string]*fakeObject
+	uploads  map[string]*fakeUpload
+	requests []string
+
+	// truncateUploads drops the last byte of uploaded objects, as a broken
+	// transfer would
+	truncateUploads bool
+	// corruptChecksums returns a wrong SHA256 checksum in the metadata of
+	// objects
+	corruptChecksums bool
+}
+
+// fakeUpload is a multipart upload in progress.
+type fakeUpload struct {
+	header http.Header
+	parts  map[int][]byte
+}
+
+type fakeObject struct {
+	body   []byte
+	etag   string
+	header http.Header
+}
+
+// md5Hex returns the hex encoded MD5 checksum of b, the ETag of a single
+// part upload.
+func md5Hex(b []byte) string {
+	sum := md5.Sum(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// sha256Hex returns the hex encoded SHA256 checksum of b.
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func newFakeSpaces() *fakeSpaces {
+	return &fakeSpaces{
+		objects: make(map[string]*fakeObject),
+		uploads: make(map[string]*fakeUpload),
+	}
+}
+
+func (f *fakeSpaces) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+	f.requests = append(f.requests, r.Method+" "+r.URL.Path)
+
+	switch {
+	case r.Method == http.MethodHead:
+		object, ok := f.objects[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		for k, v := range object.header {
+			w.Header()[k] = v
+		}
+		if f.corruptChecksums {
+			w.Header().Set("X-Amz-Meta-Sha256", sha256Hex([]byte("other")))
+		}
+		w.Header().Set("ETag", `"`+object.etag+`"`)
+		w.Header().Set("Content-Length", strconv.Itoa(len(object.body)))
+	case r.Method == http.MethodPost && r.URL.Query().Has("uploads"):
+		f.uploads[r.URL.Path] = &fakeUpload{header: f.objectHeader(r), parts: make(map[int][]byte)}
+		_, _ = fmt.Fprintf(w, "<InitiateMultipartUploadResult><UploadId>%s</UploadId></InitiateMultipartUploadResult>", r.URL.Path)
+	case r.Method == http.MethodPut && r.URL.Query().Has("partNumber"):
+		part, _ := strconv.Atoi(r.URL.Query().Get("partNumber"))
+		body, _ := io.ReadAll(r.Body)
+		body = f.received(body)
+		f.uploads[r.URL.Path].parts[part] = body
+		w.Header().Set("ETag", `"`+md5Hex(body)+`"`)
+	case r.Method == http.MethodPost && r.URL.Query().Has("uploadId"):
+		upload := f.uploads[r.URL.Path]
+		var body []byte
+		sums := md5.New()
+		for i := 1; i <= len(upload.parts); i++ {
+			body = append(body, upload.parts[i]...)
+			sum := md5.Sum(upload.parts[i])
+			sums.Write(sum[:])
+		}
+		etag := fmt.Sprintf("%x-%d", sums.Sum(nil), len(upload.parts))
+		f.objects[r.URL.Path] = &fakeObject{body: body, etag: etag, header: upload.header}
+		delete(f.uploads, r.URL.Path)
+		_, _ = fmt.Fprintf(w, `<CompleteMultipartUploadResult><ETag>"%s"</ETag></CompleteMultipartUploadResult>`, etag)
+	case r.Method == http.MethodPut:
+		body, _ := io.ReadAll(r.Body)
+		body = f.received(body)
+		f.objects[r.URL.Path] = &fakeObject{body: body, etag: md5Hex(body), header: f.objectHeader(r)}
+		w.Header().Set("ETag", `"`+md5Hex(body)+`"`)
+	case r.Method == http.MethodDelete:
+		delete(f.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// objectHeader returns the ACL and metadata headers of an upload request.
+func (f *fakeSpaces) objectHeader(r *http.Request) http.Header {
+	header := http.Header{}
+	for k, v := range r.Header {
+		if strings.HasPrefix(k, "X-Amz-Meta-") || k == "X-Amz-Acl" {
+			header[k] = v
+		}
+	}
+
+	return header
+}
+
+// received returns the part of an uploaded body the server received.
+func (f *fakeSpaces) received(body []byte) []byte {
+	if f.truncateUploads && len(body) > 0 {
+		return body[:len(body)-1]
+	}
+
+	return body
+}
+
+// fakeAPI is a DigitalOcean API importing images instantly.
+type fakeAPI struct {
+	sync.Mutex
+	createRequests []map[string]interface{}
+	transfers      []string
+	actionChecks   int
+}
+
+func (f *fakeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/v2/images":
+		var req map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		f.createRequests = append(f.createRequests, req)
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"image":{"id":42,"name":"imported","status":"NEW"}}`))
+	case r.Method == http.MethodGet && r.URL.Path == "/v2/images/42":
+		_, _ = w.Write([]byte(`{"image":{"id":42,"name":"imported","status":"available","regions":["nyc3"]}}`))
+	case r.Method == http.MethodPost && r.URL.Path == "/v2/images/42/actions":
+		var req map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		f.transfers = append(f.transfers, req["region"].(string))
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"action":{"id":7,"status":"in-progress"}}`))
+	case r.Method == http.MethodGet && r.URL.Path == "/v2/images/42/actions/7":
+		// Transfers complete on the second check.
+		f.actionChecks++
+		status := "in-progress"
+		if f.actionChecks%2 == 0 {
+			status = "completed"
+		}
+		_, _ = w.Write([]byte(fmt.Sprintf(`{"action":{"id":7,"status":%q}}`, status)))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"id":"not_found","message":"not found"}`))
+	}
+}
+
+// testImageContent is the content of the image file imported by testImport.
+var testImageContent = []byte(strings.Repeat("x", 1024))
+
+// testImport runs the post-processor against the fake Spaces server and a
+// fake API, importing a small image file.
+func testImport(t *testing.T, spaces *fakeSpaces, config map[string]interface{}) (*fakeAPI, packersdk.Artifact, error) {
+	return testImportWithUi(t, packersdk.TestUi(t), spaces, config, testImageContent)
+}
+
+// testImportWithUi is testImport with a Ui and an image content.
+func testImportWithUi(t *testing.T, ui packersdk.Ui, spaces *fakeSpaces, config map[string]interface{}, content []byte) (*fakeAPI, packersdk.Artifact, error) {
+	spacesServer := httptest.NewServer(spaces)
+	t.Cleanup(spacesServer.Close)
+	api := &fakeAPI{}
+	apiServer := httptest.NewServer(api)
+	t.Cleanup(apiServer.Close)
+
+	image := filepath.Join(t.TempDir(), "disk.raw")
+	require.NoError(t, os.WriteFile(image, content, 0644))
+
+	config["api_url"] = apiServer.URL
+	config["spaces_endpoint"] = spacesServer.URL
+	config["spaces_force_path_style"] = true
+
+	var p PostProcessor
+	require.NoError(t, p.Configure(config))
+
+	artifact, _, _, err := p.PostProcess(context.Background(), ui,
+		&packersdk.MockArtifact{FilesValue: []string{image}})
+	return api, artifact, err
+}
+
+func TestPostProcessor_PostProcess(t *testing.T) {
+	spaces := newFakeSpaces()
+	api, artifact, err := testImport(t, spaces, testConfig())
+	require.NoError(t, err)
+	require.Equal(t, "nyc3:42", artifact.Id())
+
+	require.Equal(t, []string{
+		"HEAD /bucket/image.raw", "PUT /bucket/image.raw", "HEAD /bucket/image.raw", "DELETE /bucket/image.raw",
+	}, spaces.requests)
+	require.Len(t, api.createRequests, 1)
+	require.Equal(t, "nyc3", api.createRequests[0]["region"])
+
+	u, err := url.Parse(api.createRequests[0]["url"].(string))
+	require.NoError(t, err)
+	require.Equal(t, "/bucket/image.raw", u.Path)
+	require.Equal(t, "1200", u.Query().Get("X-Amz-Expires"))
+	require.NotEmpty(t, u.Query().Get("X-Amz-Signature"))
+}
+
+func TestPostProcessor_PostProcess_Distribute(t *testing.T) {
+	config := testConfig()
+	config["image_regions"] = []string{"nyc3", "sfo3", "ams3"}
+	config["poll_interval"] = "10ms"
+
+	start := time.Now()
+	api, _, err := testImport(t, newFakeSpaces(), config)
+	require.NoError(t, err)
+	require.Equal(t, []string{"ams3", "sfo3"}, api.transfers)
+	require.Equal(t, 4, api.actionChecks)
+	// The transfer waits poll every 10ms instead of the default 3s.
+	require.Less(t, time.Since(start), docommon.DefaultPollInterval)
+}
+
+func TestPostProcessor_PostProcess_PublicRead(t *testing.T) {
+	config := testConfig()
+	config["spaces_acl"] = "public-read"
+	config["skip_clean"] = true
+	spaces := newFakeSpaces()
+	api, _, err := testImport(t, spaces, config)
+	require.NoError(t, err)
+
+	object := spaces.objects["/bucket/image.raw"]
+	require.Equal(t, "public-read", object.header.Get("X-Amz-Acl"))
+	require.Equal(t, sha256Hex(testImageContent), object.header.Get("X-Amz-Meta-Sha256"))
+	require.Len(t, api.createRequests, 1)
+	require.True(t, strings.HasSuffix(api.createRequests[0]["url"].(string), "/bucket/image.raw"))
+}
+
+func TestPostProcessor_PostProcess_SkipUpload(t *testing.T) {
+	uploaded := []string{
+		"HEAD /bucket/image.raw", "PUT /bucket/image.raw", "HEAD /bucket/image.raw", "DELETE /bucket/image.raw",
+	}
+	tests := []struct {
+		name     string
+		checksum string
+		requests []string
+	}{
+		{
+			name:     "same checksum",
+			checksum: sha256Hex(testImageContent),
+			requests: []string{"HEAD /bucket/image.raw", "DELETE /bucket/image.raw"},
+		},
+		{
+			name:     "other checksum",
+			checksum: sha256Hex([]byte("other")),
+			requests: uploaded,
+		},
+		{
+			name:     "no checksum",
+			requests: uploaded,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			if tt.checksum != "" {
+				header.Set("X-Amz-Meta-Sha256", tt.checksum)
+			}
+			spaces := newFakeSpaces()
+			// The ETag of an upload in other parts does not matter
+			spaces.objects["/bucket/image.raw"] = &fakeObject{
+				body: testImageContent, etag: md5Hex(testImageContent) + "-2", header: header,
+			}
+
+			api, _, err := testImport(t, spaces, testConfig())
+			require.NoError(t, err)
+			require.Equal(t, tt.requests, spaces.requests)
+			require.Len(t, api.createRequests, 1)
+		})
+	}
+}
+
+func TestPostProcessor_PostProcess_Multipart(t *testing.T) {
+	// Three parts of 5 MiB
+	content := bytes.Repeat([]byte("0123456789abcdef"), 12*1024*1024/16)
+	config := testConfig()
+	config["spaces_upload_part_size"] = 5
+	config["spaces_upload_concurrency"] = 2
+	config["skip_clean"] = true
+
+	spaces := newFakeSpaces()
+	ui := &packersdk.MockUi{}
+	_, _, err := testImportWithUi(t, ui, spaces, config, content)
+	require.NoError(t, err)
+	require.True(t, ui.TrackProgressCalled)
+
+	parts := 0
+	for _, r := range spaces.requests {
+		if r == "PUT /bucket/image.raw" {
+			parts++
+		}
+	}
+	require.Equal(t, 3, parts)
+	require.Equal(t, content, spaces.objects["/bucket/image.raw"].body)
+	require.Regexp(t, "^[0-9a-f]{32}-3$", spaces.objects["/bucket/image.raw"].etag)
+
+	// The object is not uploaded again, even in parts of another size
+	spaces.requests = nil
+	config["spaces_upload_part_size"] = 6
+	_, _, err = testImportWithUi(t, ui, spaces, config, content)
+	require.NoError(t, err)
+	require.Equal(t, []string{"HEAD /bucket/image.raw"}, spaces.requests)
+}
+
+func TestPostProcessor_PostProcess_ChecksumMismatch(t *testing.T) {
+	tests := []struct {
+		name             string
+		content          []byte
+		truncateUploads  bool
+		corruptChecksums bool
+		expected         string
+	}{
+		{
+			name:            "single part",
+			content:         testImageContent,
+			truncateUploads: true,
+			expected:        "expected ETag",
+		},
+		{
+			name:            "multipart",
+			content:         bytes.Repeat([]byte("x"), 6*1024*1024),
+			truncateUploads: true,
+			expected:        "expected ETag",
+		},
+		{
+			name:             "checksum metadata",
+			content:          testImageContent,
+			corruptChecksums: true,
+			expected:         "expected SHA256 " + sha256Hex(testImageContent),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spaces := newFakeSpaces()
+			spaces.truncateUploads = tt.truncateUploads
+			spaces.corruptChecksums = tt.corruptChecksums
+			api, _, err := testImportWithUi(t, packersdk.TestUi(t), spaces, testConfig(), tt.content)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), "Checksum mismatch")
+			require.Contains(t, err.Error(), tt.expected)
+			require.Empty(t, api.createRequests)
+		})
+	}
+}
+
+func TestFileChecksums(t *testing.T) {
+	multipartETag := func(parts ...string) string {
+		sums := md5.New()
+		for _, part := range parts {
+			sum := md5.Sum([]byte(part))
+			sums.Write(sum[:])
+		}
+		return fmt.Sprintf("%x-%d", sums.Sum(nil), len(parts))
+	}
+
+	tests := []struct {
+		name     string
+		partSize int64
+		expected string
+	}{
+		{name: "single part", partSize: 11, expected: md5Hex([]byte("0123456789"))},
+		{name: "one part", partSize: 10, expected: multipartETag("0123456789")},
+		{name: "uneven parts", partSize: 4, expected: multipartETag("0123", "4567", "89")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sum, etag, err := fileChecksums(strings.NewReader("0123456789"), 10, tt.partSize)
+			require.NoError(t, err)
+			require.Equal(t, sha256Hex([]byte("0123456789")), sum)
+			require.Equal(t, tt.expected, etag)
+		})
+	}
+}
+
+func TestPostProcessor_Configure_Upload(t *testing.T) {
+	var p PostProcessor
+	require.NoError(t, p.Configure(testConfig()))
+	require.Equal(t, int64(5), p.config.SpacesUploadPartSize)
+	require.Equal(t, 5, p.config.SpacesUploadConcurrency)
+
+	for k, v := range map[string]interface{}{
+		"spaces_upload_part_size":   1,
+		"spaces_upload_concurrency": -1,
+	} {
+		config := testConfig()
+		config[k] = v
+		p = PostProcessor{}
+		require.Error(t, p.Configure(config), k)
+	}
+}
+
+func TestPostProcessor_PostProcess_ImageURL(t *testing.T) {
+	t.Run("https", func(t *testing.T) {
+		config := map[string]interface{}{
+			"api_token":     "token",
+			"image_name":    "imported",
+			"image_regions": []string{"nyc3"},
+			"image_url":     "https://cloud-images.example.com/jammy.img",
+		}
+		spaces := newFakeSpaces()
+		api, artifact, err := testImport(t, spaces, config)
+		require.NoError(t, err)
+		require.Equal(t, "nyc3:42", artifact.Id())
+
+		require.Empty(t, spaces.requests)
+		require.Len(t, api.createRequests, 1)
+		require.Equal(t, "https://cloud-images.example.com/jammy.img", api.createRequests[0]["url"])
+	})
+
+	t.Run("spaces", func(t *testing.T) {
+		config := testConfig()
+		delete(config, "space_name")
+		delete(config, "space_object_name")
+		config["image_url"] = "spaces://images/vendor/disk.qcow2"
+		spaces := newFakeSpaces()
+		api, _, err := testImport(t, spaces, config)
+		require.NoError(t, err)
+
+		require.Empty(t, spaces.requests)
+		require.Len(t, api.createRequests, 1)
+		u, err := url.Parse(api.createRequests[0]["url"].(string))
+		require.NoError(t, err)
+		require.Equal(t, "/images/vendor/disk.qcow2", u.Path)
+		require.NotEmpty(t, u.Query().Get("X-Amz-Signature"))
+	})
+}
+
+func TestPostProcessor_Configure_ImageURL(t *testing.T) {
+	tests := map[string]map[string]interface{}{
+		"http URL": {
+			"image_url": "http://cloud-images.example.com/jammy.img",
+		},
+		"spaces URL without key": {
+			"image_url": "spaces://images",
+		},
+		"with space_name": {
+			"image_url":  "https://cloud-images.example.com/jammy.img",
+			"space_name": "bucket",
+		},
+	}
+
+	for name, extra := range tests {
+		t.Run(name, func(t *testing.T) {
+			config := map[string]interface{}{
+				"api_token":     "token",
+				"spaces_key":    "key",
+				"spaces_secret": "secret",
+				"spaces_region": "nyc3",
+				"image_name":    "imported",
+				"image_regions": []string{"nyc3"},
+			}
+			for k, v := range extra {
+				config[k] = v
+			}
+
+			var p PostProcessor
+			require.Error(t, p.Configure(config))
+		})
+	}
+}
+
+func TestPostProcessor_Configure_SpacesACL(t *testing.T) {
+	var p PostProcessor
+	require.NoError(t, p.Configure(testConfig()))
+	require.Equal(t, "private", p.config.SpacesACL)
+
+	config := testConfig()
+	config["spaces_acl"] = "authenticated-read"
+	p = PostProcessor{}
+	require.Error(t, p.Configure(config))
+}
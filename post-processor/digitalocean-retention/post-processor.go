@@ -0,0 +1,231 @@
+//go:generate packer-sdc struct-markdown
+//go:generate packer-sdc mapstructure-to-hcl2 -type Config
+
+package digitaloceanretention
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/digitalocean/godo"
+
+	"github.com/digitalocean/packer-plugin-digitalocean/builder/digitalocean"
+	docommon "github.com/digitalocean/packer-plugin-digitalocean/common"
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/packer-plugin-sdk/common"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+)
+
+const BuilderId = "packer.post-processor.digitalocean-retention"
+
+type Config struct {
+	common.PackerConfig   `mapstructure:",squash"`
+	docommon.AccessConfig `mapstructure:",squash"`
+
+	// Only consider snapshots whose name starts with this prefix, such as
+	// `packer-`. Only one of `name_prefix` or `name_regex` may be provided.
+	NamePrefix string `mapstructure:"name_prefix"`
+	// Only consider snapshots whose name matches this regex. Only one of
+	// `name_prefix` or `name_regex` may be provided.
+	NameRegex string `mapstructure:"name_regex"`
+	// Only consider snapshots with all of these tags. At least one of
+	// `name_prefix`, `name_regex` or `tags` must be provided.
+	Tags []string `mapstructure:"tags"`
+	// The number of most recently created snapshots to keep, including the
+	// snapshot from the current build.
+	KeepLatest int `mapstructure:"keep_latest"`
+	// Keep snapshots created within this duration (e.g. `720h`). When used
+	// with `keep_latest`, a snapshot is kept if either condition holds. At
+	// least one of `keep_latest` or `max_age` must be provided.
+	MaxAge time.Duration `mapstructure:"max_age"`
+	// Only report the snapshots that would be deleted, without deleting
+	// them. Defaults to `false`.
+	DryRun bool `mapstructure:"dry_run"`
+}
+
+type PostProcessor struct {
+	config Config
+}
+
+func (p *PostProcessor) ConfigSpec() hcldec.ObjectSpec { return p.config.FlatMapstructure().HCL2Spec() }
+
+func (p *PostProcessor) Configure(raws ...interface{}) error {
+	err := config.Decode(&p.config, &config.DecodeOpts{
+		PluginType:  BuilderId,
+		Interpolate: true,
+	}, raws...)
+	if err != nil {
+		return err
+	}
+
+	errs := new(packersdk.MultiError)
+
+	warns, es := p.config.AccessConfig.Prepare()
+	for _, w := range warns {
+		log.Printf("[WARN] %s", w)
+	}
+	if len(es) > 0 {
+		errs = packersdk.MultiErrorAppend(errs, es...)
+	}
+
+	if p.config.NamePrefix == "" && p.config.NameRegex == "" && len(p.config.Tags) == 0 {
+		errs = packersdk.MultiErrorAppend(
+			errs, errors.New("one of name_prefix, name_regex or tags must be set"))
+	}
+
+	if p.config.NamePrefix != "" && p.config.NameRegex != "" {
+		errs = packersdk.MultiErrorAppend(
+			errs, errors.New("only one of name_prefix or name_regex can be set"))
+	}
+
+	if p.config.NameRegex != "" {
+		if _, err := regexp.Compile(p.config.NameRegex); err != nil {
+			errs = packersdk.MultiErrorAppend(
+				errs, fmt.Errorf("invalid name_regex: %s", err))
+		}
+	}
+
+	if p.config.KeepLatest == 0 && p.config.MaxAge == 0 {
+		errs = packersdk.MultiErrorAppend(
+			errs, errors.New("one of keep_latest or max_age must be set"))
+	}
+
+	if p.config.KeepLatest < 0 {
+		errs = packersdk.MultiErrorAppend(
+			errs, errors.New("keep_latest must be positive"))
+	}
+
+	if p.config.MaxAge < 0 {
+		errs = packersdk.MultiErrorAppend(
+			errs, errors.New("max_age must be positive"))
+	}
+
+	if len(errs.Errors) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func (p *PostProcessor) PostProcess(ctx context.Context, ui packersdk.Ui, artifact packersdk.Artifact) (packersdk.Artifact, bool, bool, error) {
+	if artifact.BuilderId() != digitalocean.BuilderId {
+		return nil, false, false, fmt.Errorf(
+			"Unknown artifact type: %s\nCan only prune snapshots for DigitalOcean artifacts.",
+			artifact.BuilderId())
+	}
+
+	snapshotId, err := artifactSnapshotId(artifact.Id())
+	if err != nil {
+		return nil, false, false, err
+	}
+
+	client, err := p.config.Client()
+	if err != nil {
+		return nil, false, false, fmt.Errorf("DigitalOcean: could not create client, %s", err)
+	}
+
+	filter := p.imageFilter()
+	images, err := docommon.SearchImages(ctx, client, filter)
+	if err != nil {
+		return nil, false, false, fmt.Errorf("Error listing snapshots: %s", err)
+	}
+
+	expired := expiredImages(docommon.MatchImages(filter, images), snapshotId,
+		p.config.KeepLatest, p.config.MaxAge, time.Now())
+	if len(expired) == 0 {
+		ui.Say("No snapshots to prune")
+		return artifact, true, false, nil
+	}
+
+	var errs *packersdk.MultiError
+	for _, image := range expired {
+		if p.config.DryRun {
+			ui.Message(fmt.Sprintf("Would delete snapshot: %s (ID: %d, created %s, regions %s)",
+				image.Name, image.ID, image.Created, strings.Join(image.Regions, ",")))
+			continue
+		}
+
+		ui.Message(fmt.Sprintf("Deleting snapshot: %s (ID: %d, created %s, regions %s)",
+			image.Name, image.ID, image.Created, strings.Join(image.Regions, ",")))
+		if _, err := client.Images.Delete(ctx, image.ID); err != nil {
+			errs = packersdk.MultiErrorAppend(errs,
+				fmt.Errorf("Error deleting snapshot %d: %s", image.ID, err))
+		}
+	}
+
+	if errs != nil && len(errs.Errors) > 0 {
+		return nil, false, false, errs
+	}
+
+	return artifact, true, false, nil
+}
+
+// imageFilter returns the filter selecting the sibling snapshots of the
+// artifact, ordered from newest to oldest.
+func (p *PostProcessor) imageFilter() *docommon.ImageFilterOptions {
+	filter := &docommon.ImageFilterOptions{
+		Type:      "user",
+		NameRegex: p.config.NameRegex,
+		Tags:      p.config.Tags,
+		SortBy:    "created",
+	}
+	if p.config.NamePrefix != "" {
+		filter.NameRegex = "^" + regexp.QuoteMeta(p.config.NamePrefix)
+	}
+
+	return filter
+}
+
+// artifactSnapshotId parses the snapshot ID from an artifact ID of the form
+// `<regions>:<id>`.
+func artifactSnapshotId(id string) (int, error) {
+	parts := strings.Split(id, ":")
+	snapshotId, err := strconv.Atoi(parts[len(parts)-1])
+	if err != nil {
+		return 0, fmt.Errorf("Invalid artifact ID %q: %s", id, err)
+	}
+
+	return snapshotId, nil
+}
+
+// expiredImages returns the snapshots, ordered from newest to oldest, that
+// are neither among the newest keepLatest snapshots nor younger than maxAge.
+// The image with keepId is never returned, nor is any image that is not a
+// snapshot or whose creation time cannot be parsed.
+func expiredImages(images []godo.Image, keepId int, keepLatest int, maxAge time.Duration, now time.Time) []godo.Image {
+	expired := make([]godo.Image, 0)
+	snapshots := 0
+	for _, image := range images {
+		// User images also include backups and uploaded custom images,
+		// which Packer did not create
+		if image.Type != "snapshot" {
+			continue
+		}
+
+		snapshots++
+		if image.ID == keepId || snapshots <= keepLatest {
+			continue
+		}
+
+		created, err := time.Parse(time.RFC3339, image.Created)
+		if err != nil {
+			log.Printf("[WARN] Skipping snapshot %d with invalid creation time %q", image.ID, image.Created)
+			continue
+		}
+
+		if maxAge > 0 && now.Sub(created) < maxAge {
+			continue
+		}
+
+		expired = append(expired, image)
+	}
+
+	return expired
+}
@@ -0,0 +1,114 @@
+package digitaloceanretention
+
+import (
+	"testing"
+	"time"
+
+	"github.com/digitalocean/godo"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostProcessor_ImplementsPostProcessor(t *testing.T) {
+	var _ packersdk.PostProcessor = new(PostProcessor)
+}
+
+func TestPostProcessor_Configure(t *testing.T) {
+	tests := []struct {
+		name          string
+		config        map[string]interface{}
+		expectedError string
+	}{
+		{
+			name:   "valid",
+			config: map[string]interface{}{"name_prefix": "packer-", "keep_latest": 3},
+		},
+		{
+			name:          "missing selector",
+			config:        map[string]interface{}{"keep_latest": 3},
+			expectedError: "one of name_prefix, name_regex or tags must be set",
+		},
+		{
+			name:          "prefix and regex",
+			config:        map[string]interface{}{"name_prefix": "packer-", "name_regex": "^packer-", "keep_latest": 3},
+			expectedError: "only one of name_prefix or name_regex can be set",
+		},
+		{
+			name:          "missing retention",
+			config:        map[string]interface{}{"tags": []string{"golden"}},
+			expectedError: "one of keep_latest or max_age must be set",
+		},
+		{
+			name:          "invalid regex",
+			config:        map[string]interface{}{"name_regex": "[", "max_age": "720h"},
+			expectedError: "invalid name_regex",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config["api_token"] = "foo"
+			var p PostProcessor
+			err := p.Configure(tt.config)
+			if tt.expectedError == "" {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tt.expectedError)
+			}
+		})
+	}
+}
+
+func TestPostProcessor_ImageFilter(t *testing.T) {
+	p := PostProcessor{config: Config{NamePrefix: "packer-1.0"}}
+	filter := p.imageFilter()
+	require.Equal(t, `^packer-1\.0`, filter.NameRegex)
+	require.Equal(t, "user", filter.Type)
+}
+
+func TestArtifactSnapshotId(t *testing.T) {
+	id, err := artifactSnapshotId("nyc3,sfo3:1234")
+	require.NoError(t, err)
+	require.Equal(t, 1234, id)
+
+	_, err = artifactSnapshotId("nyc3:")
+	require.Error(t, err)
+}
+
+func TestExpiredImages(t *testing.T) {
+	now := time.Date(2022, 8, 11, 0, 0, 0, 0, time.UTC)
+	images := []godo.Image{
+		{ID: 5, Type: "snapshot", Created: "2022-08-10T00:00:00Z"},
+		{ID: 7, Type: "backup", Created: "2022-08-09T12:00:00Z"},
+		{ID: 4, Type: "snapshot", Created: "2022-08-09T00:00:00Z"},
+		{ID: 3, Type: "snapshot", Created: "2022-08-08T00:00:00Z"},
+		{ID: 2, Type: "snapshot", Created: "invalid"},
+		{ID: 1, Type: "snapshot", Created: "2022-08-01T00:00:00Z"},
+		{ID: 6, Type: "custom", Created: "2022-07-01T00:00:00Z"},
+	}
+
+	tests := []struct {
+		name        string
+		keepId      int
+		keepLatest  int
+		maxAge      time.Duration
+		expectedIDs []int
+	}{
+		{name: "keep latest", keepId: 5, keepLatest: 2, expectedIDs: []int{3, 1}},
+		{name: "max age", keepId: 5, maxAge: 60 * time.Hour, expectedIDs: []int{3, 1}},
+		{name: "keep latest or max age", keepId: 5, keepLatest: 1, maxAge: 80 * time.Hour, expectedIDs: []int{1}},
+		{name: "current snapshot is never deleted", keepId: 1, keepLatest: 1, expectedIDs: []int{4, 3}},
+		{name: "backups and custom images are never deleted", keepId: 5, maxAge: time.Hour, expectedIDs: []int{4, 3, 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ids := make([]int, 0)
+			for _, i := range expiredImages(images, tt.keepId, tt.keepLatest, tt.maxAge, now) {
+				ids = append(ids, i.ID)
+			}
+			require.Equal(t, tt.expectedIDs, ids)
+		})
+	}
+}